@@ -0,0 +1,331 @@
+package xrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// batchReply collects the responses of the entries done while the batch is being dispatched,
+// and sends them in one message. The entries done later are replied alone as soon as they are done.
+type batchReply struct {
+	server      *RPCServer
+	replyTo     string
+	mu          sync.Mutex
+	dispatching bool
+	responses   []*xrpcpb.Response
+}
+
+// done records the response of an entry, or sends it if the batch is dispatched
+func (b *batchReply) done(response *xrpcpb.Response) {
+	b.mu.Lock()
+	if b.dispatching {
+		b.responses = append(b.responses, response)
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+
+	b.send([]*xrpcpb.Response{response})
+}
+
+// flush sends the responses collected after all the entries are dispatched
+func (b *batchReply) flush() {
+	b.mu.Lock()
+	b.dispatching = false
+	responses := b.responses
+	b.responses = nil
+	b.mu.Unlock()
+
+	b.send(responses)
+}
+
+func (b *batchReply) send(responses []*xrpcpb.Response) {
+	if b.replyTo == "" || len(responses) == 0 {
+		return
+	}
+
+	response := &xrpcpb.Response{
+		Batch:     responses,
+		Responder: b.server.opts.serverID,
+	}
+	if len(responses) == 1 {
+		response = responses[0]
+	}
+	data, err := proto.Marshal(response)
+	if err != nil {
+		glog.Error("proto.Marshal error: ", err)
+		return
+	}
+
+	err = b.server.opts.mq.Publish(b.replyTo, data)
+	if err != nil {
+		glog.Error("mq.Publish error: ", err)
+	}
+}
+
+// handleBatch dispatches the entries of the batch as if they were sent alone,
+// the Register methods run in order and the RegisterGO methods run concurrently.
+func (s *RPCServer) handleBatch(start time.Time, envelope *xrpcpb.Request) {
+	b := &batchReply{
+		server:      s,
+		replyTo:     envelope.ReplyTo,
+		dispatching: true,
+	}
+
+	for _, request := range envelope.Batch {
+		s.batches.Store(request, b)
+		if request.Stream {
+			s.replyError(request, Errorf(FailedPrecondition, "stream not supported in batch: %s", request.Method))
+			continue
+		}
+
+		if !s.dispatch(start, request) {
+			s.batches.Delete(request)
+		}
+	}
+	b.flush()
+}
+
+// Batch queues the calls to the server subscribing subj, and sends them in one message, e.g.
+//
+//	batch := client.NewBatch(subj)
+//	var sum int
+//	call := batch.Go("Add", &sum, 1, 2)
+//	batch.Cast("Log", "message")
+//	err := batch.Do(ctx)
+//
+// The replies of the calls done while the server dispatches the batch, e.g. the Register methods,
+// are sent back together. The others, e.g. the RegisterGO methods, are replied as soon as each is done,
+// so a slow call doesn't delay the others.
+// The client interceptors and retry policy are not applied.
+// goroutine safe
+type Batch struct {
+	c       *RPCClient
+	subj    string
+	mu      sync.Mutex
+	entries []*batchEntry
+}
+
+type batchEntry struct {
+	request *xrpcpb.Request
+	call    *Call // nil for Cast
+}
+
+// NewBatch returns an empty batch of the calls to subj
+func (c *RPCClient) NewBatch(subj string) *Batch {
+	return &Batch{
+		c:    c,
+		subj: subj,
+	}
+}
+
+// Len returns the number of the calls queued
+func (b *Batch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Go queues the call of the method, the returned Call is done after the batch is sent and the reply arrives
+func (b *Batch) Go(methodName string, reply any, args ...any) *Call {
+	call := &Call{
+		Subj:       b.subj,
+		MethodName: methodName,
+		Args:       args,
+		Reply:      reply,
+		Done:       make(chan *Call, 1),
+		done:       make(chan struct{}),
+	}
+
+	request, err := b.newRequest(methodName, args)
+	if err != nil {
+		call.finish(err)
+		return call
+	}
+
+	b.mu.Lock()
+	b.entries = append(b.entries, &batchEntry{request: request, call: call})
+	b.mu.Unlock()
+	return call
+}
+
+// Cast queues the call of the method without reply
+func (b *Batch) Cast(methodName string, args ...any) error {
+	request, err := b.newRequest(methodName, args)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.entries = append(b.entries, &batchEntry{request: request})
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *Batch) newRequest(methodName string, args []any) (*xrpcpb.Request, error) {
+	argsData, err := b.c.marshalArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xrpcpb.Request{
+		Method: methodName,
+		Params: argsData,
+	}, nil
+}
+
+// Send sends the queued calls in one message and empties the batch, it doesn't wait for the replies.
+// The calls give up when ctx is done, if ctx has no deadline, the timeout of options is used.
+// The calls are done with the error if it fails.
+func (b *Batch) Send(ctx context.Context) error {
+	return b.sendEntries(ctx, b.take())
+}
+
+// take empties the batch and returns the calls queued
+func (b *Batch) take() []*batchEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := b.entries
+	b.entries = nil
+	return entries
+}
+
+// sendEntries sends the entries, the calls are done with the error if it fails
+func (b *Batch) sendEntries(ctx context.Context, entries []*batchEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := b.send(ctx, entries)
+	if err != nil {
+		for _, entry := range entries {
+			if entry.call != nil {
+				entry.call.finish(err)
+			}
+		}
+	}
+	return err
+}
+
+func (b *Batch) send(ctx context.Context, entries []*batchEntry) error {
+	c := b.c
+	if !c.isValid {
+		err := c.retry()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel, defaultTimeout := c.withDefaultTimeout(ctx)
+
+	remaining, err := remainingTimeout(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	md, _ := FromOutgoingContext(ctx)
+	envelope := &xrpcpb.Request{
+		ReplyTo: c.opts.subj,
+		Batch:   make([]*xrpcpb.Request, len(entries)),
+	}
+	var calls []*batchEntry
+	for i, entry := range entries {
+		entry.request.Timeout = remaining
+		entry.request.Metadata = md
+		if entry.call != nil {
+			randCid, err := uuid.NewRandom()
+			if err != nil {
+				cancel()
+				return err
+			}
+			entry.request.Cid = randCid.String()
+			entry.request.ReplyTo = c.opts.subj
+			calls = append(calls, entry)
+		}
+		envelope.Batch[i] = entry.request
+	}
+
+	data, err := proto.Marshal(envelope)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	var wg sync.WaitGroup
+	dones := make([]chan *xrpcpb.Response, len(calls))
+	for i, entry := range calls {
+		dones[i] = make(chan *xrpcpb.Response, 1)
+		c.calls.Store(entry.request.Cid, dones[i])
+	}
+
+	err = c.opts.mq.Publish(b.subj, data)
+	if err != nil {
+		for _, entry := range calls {
+			c.calls.Delete(entry.request.Cid)
+		}
+		cancel()
+		return err
+	}
+
+	// wait for the replies
+	for i, entry := range calls {
+		wg.Add(1)
+		go func(entry *batchEntry, doneChan chan *xrpcpb.Response) {
+			defer wg.Done()
+			defer c.calls.Delete(entry.request.Cid)
+
+			select {
+			case <-ctx.Done():
+				if defaultTimeout && ctx.Err() == context.DeadlineExceeded {
+					entry.call.finish(ErrTimeout)
+				} else {
+					entry.call.finish(ctx.Err())
+				}
+			case response := <-doneChan:
+				err := c.responseError(response)
+				if err == nil {
+					err = c.decodeReply(response, entry.call.Reply)
+				}
+				entry.call.finish(err)
+			}
+		}(entry, dones[i])
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+	}()
+	return nil
+}
+
+// Do sends the queued calls like Send, and waits for all the replies.
+// It returns the first error of the calls in order.
+func (b *Batch) Do(ctx context.Context) error {
+	// the calls queued concurrently after are left to the next Send
+	entries := b.take()
+	if err := b.sendEntries(ctx, entries); err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, entry := range entries {
+		if entry.call == nil {
+			continue
+		}
+		if err := entry.call.Wait(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,172 @@
+package xrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+
+	"github.com/google/uuid"
+)
+
+// Call represents an asynchronous rpc call started by Go
+type Call struct {
+	Subj       string
+	MethodName string
+	Args       []any
+	Reply      any        // the reply of the method, valid after the call is done without error
+	Error      error      // the error of the call, valid after the call is done
+	Done       chan *Call // receives the call itself when it is done
+	done       chan struct{}
+
+	// set if the call is completed by the reply, see RPCClient.goCall
+	c        *RPCClient
+	cid      string
+	mu       sync.Mutex
+	timer    *time.Timer // the default timeout
+	trailer  *Metadata
+	complete sync.Once
+}
+
+// Wait waits for the call to be done and returns its error
+func (call *Call) Wait() error {
+	<-call.done
+	return call.Error
+}
+
+func (call *Call) finish(err error) {
+	call.Error = err
+	close(call.done)
+	call.Done <- call
+}
+
+// reply completes the call with the response, or err if response is nil.
+// Only the first of the reply, the timeout and the cancel of ctx is taken.
+func (call *Call) reply(response *xrpcpb.Response, err error) {
+	call.complete.Do(func() {
+		call.c.calls.Delete(call.cid)
+		call.mu.Lock()
+		if call.timer != nil {
+			call.timer.Stop()
+		}
+		call.mu.Unlock()
+
+		if response != nil {
+			if call.trailer != nil {
+				*call.trailer = response.Metadata
+			}
+			err = call.c.responseError(response)
+			if err == nil {
+				err = call.c.decodeReply(response, call.Reply)
+			}
+		}
+		call.finish(err)
+	})
+}
+
+// Go calls the rpc method asynchronously, the returned Call is done when the reply arrives or it fails.
+// goroutine safe
+func (c *RPCClient) Go(subj string, methodName string, reply any, args ...any) *Call {
+	return c.GoContext(context.Background(), subj, methodName, reply, args...)
+}
+
+// GoContext is like Go, but the call gives up when ctx is done, see CallContext.
+// The call is completed by the reply and the default timeout without a goroutine waiting for it,
+// only a ctx which can be done is watched by a goroutine.
+// If the client interceptors or the retry policy apply, the call runs in a new goroutine.
+// goroutine safe
+func (c *RPCClient) GoContext(ctx context.Context, subj string, methodName string, reply any, args ...any) *Call {
+	call := &Call{
+		Subj:       subj,
+		MethodName: methodName,
+		Args:       args,
+		Reply:      reply,
+		Done:       make(chan *Call, 1),
+		done:       make(chan struct{}),
+	}
+
+	if c.opts.clientInterceptor != nil || (c.opts.retryPolicy != nil && c.opts.retryPolicy.enabled(methodName)) {
+		go func() {
+			call.finish(c.CallContext(ctx, subj, methodName, reply, args...))
+		}()
+		return call
+	}
+
+	if !c.isValid {
+		if err := c.retry(); err != nil {
+			call.finish(err)
+			return call
+		}
+	}
+	if err := c.goCall(ctx, call); err != nil {
+		call.finish(err)
+	}
+	return call
+}
+
+// goCall sends the request of call, which is completed by handleResponse
+func (c *RPCClient) goCall(ctx context.Context, call *Call) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// use the default timeout if ctx has no deadline, a timer instead of withDefaultTimeout
+	// so that no goroutine watches the ctx
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.opts.timeout)
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return context.DeadlineExceeded
+	}
+
+	argsData, err := c.marshalArgs(call.Args)
+	if err != nil {
+		return err
+	}
+
+	randCid, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+
+	md, _ := FromOutgoingContext(ctx)
+	request := &xrpcpb.Request{
+		Cid:      randCid.String(),
+		ReplyTo:  c.opts.subj,
+		Method:   call.MethodName,
+		Params:   argsData,
+		Timeout:  int64(remaining),
+		Metadata: md,
+	}
+
+	call.c = c
+	call.cid = request.Cid
+	call.trailer = trailerReceiver(ctx)
+	c.calls.Store(call.cid, call)
+
+	if !ok {
+		call.mu.Lock()
+		call.timer = time.AfterFunc(remaining, func() {
+			call.reply(nil, ErrTimeout)
+		})
+		call.mu.Unlock()
+	}
+	// only a cancelable ctx needs to be watched
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				call.reply(nil, ctx.Err())
+			case <-call.done:
+			}
+		}()
+	}
+
+	if err := c.publish(call.Subj, request, deadline); err != nil {
+		call.reply(nil, err)
+	}
+	return nil
+}
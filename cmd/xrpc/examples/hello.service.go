@@ -14,6 +14,7 @@ func RegisterHelloServiceServer(rpc *xrpc.RPCServer, s IHelloService) {
 	rpc.RegisterGO("Hello", s.Hello)
 	rpc.Register("Add", s.Add)
 	rpc.Register("Print", s.Print)
+	rpc.SetServingStatus("HelloService", xrpc.Serving)
 }
 
 type HelloServiceClient struct {
@@ -31,18 +32,33 @@ func (c *HelloServiceClient) Hello(subj string, arg0 string) (string, error) {
     return reply, err
 }
 
+func (c *HelloServiceClient) HelloAsync(subj string, arg0 string) *xrpc.Call {
+    var reply string
+    return c.c.Go(subj, "Hello", &reply, arg0)
+}
+
 func (c *HelloServiceClient) Add(subj string, arg0 *string, arg1 []byte) (*string, error) {
     var reply string
     err := c.c.Call(subj, "Add", &reply, arg0, arg1)
     return &reply, err
 }
 
+func (c *HelloServiceClient) AddAsync(subj string, arg0 *string, arg1 []byte) *xrpc.Call {
+    var reply string
+    return c.c.Go(subj, "Add", &reply, arg0, arg1)
+}
+
 func (c *HelloServiceClient) Print(subj string, arg0 *string, arg1 []byte) ([]byte, error) {
     var reply []byte
     err := c.c.Call(subj, "Print", &reply, arg0, arg1)
     return reply, err
 }
 
+func (c *HelloServiceClient) PrintAsync(subj string, arg0 *string, arg1 []byte) *xrpc.Call {
+    var reply []byte
+    return c.c.Go(subj, "Print", &reply, arg0, arg1)
+}
+
 type IWorldService interface {
     Hi()
     Sum() (int, error)
@@ -51,6 +67,7 @@ type IWorldService interface {
 func RegisterWorldServiceServer(rpc *xrpc.RPCServer, s IWorldService) {
 	rpc.Register("Hi", s.Hi)
 	rpc.RegisterGO("Sum", s.Sum)
+	rpc.SetServingStatus("WorldService", xrpc.Serving)
 }
 
 type WorldServiceClient struct {
@@ -72,3 +89,8 @@ func (c *WorldServiceClient) Sum(subj string) (int, error) {
     err := c.c.Call(subj, "Sum", &reply)
     return reply, err
 }
+
+func (c *WorldServiceClient) SumAsync(subj string) *xrpc.Call {
+    var reply int
+    return c.c.Go(subj, "Sum", &reply)
+}
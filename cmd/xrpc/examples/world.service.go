@@ -13,6 +13,7 @@ type IHelloPbService interface {
 func RegisterHelloPbServiceServer(rpc *xrpc.RPCServer, s IHelloPbService) {
 	rpc.Register("Hello", s.Hello)
 	rpc.Register("Add", s.Add)
+	rpc.SetServingStatus("HelloPbService", xrpc.Serving)
 }
 
 type HelloPbServiceClient struct {
@@ -30,8 +31,18 @@ func (c *HelloPbServiceClient) Hello(subj string, arg0 *pb.String) (*pb.String,
     return &reply, err
 }
 
+func (c *HelloPbServiceClient) HelloAsync(subj string, arg0 *pb.String) *xrpc.Call {
+    var reply pb.String
+    return c.c.Go(subj, "Hello", &reply, arg0)
+}
+
 func (c *HelloPbServiceClient) Add(subj string, arg0 *pb.String, arg1 *pb.String) (*pb.String, error) {
     var reply pb.String
     err := c.c.Call(subj, "Add", &reply, arg0, arg1)
     return &reply, err
 }
+
+func (c *HelloPbServiceClient) AddAsync(subj string, arg0 *pb.String, arg1 *pb.String) *xrpc.Call {
+    var reply pb.String
+    return c.c.Go(subj, "Add", &reply, arg0, arg1)
+}
@@ -10,11 +10,38 @@ import (
 	"github.com/yc90s/xrpc"
 )
 
+// tmplArgs passes the args of a client method to xrpc,
+// the variadic arg is spread since each of its elements is a param of the server method
+const tmplArgs = `
+{{- define "prelude"}}
+{{- if .IsVariadic}}
+    args := []any{
+	{{- range $index, $_ := .CallArgs}}
+		{{- if ne $index (sub (len $.CallArgs) 1)}}{{if $index}}, {{end}}arg{{$index}}{{end}}
+	{{- end -}}
+	}
+    for _, arg := range arg{{sub (len .CallArgs) 1}} {
+        args = append(args, arg)
+    }
+{{- end}}
+{{- end}}
+
+{{- define "args"}}
+{{- if .IsVariadic}}, args...
+{{- else}}
+	{{- range $index, $_ := .CallArgs}}, arg{{$index}}{{end}}
+{{- end}}
+{{- end}}
+`
+
 const tmplService = `// Code generated by xrpc. DO NOT EDIT.
 {{$root := . -}}
 package {{$root.Name}}
 
 import "github.com/yc90s/xrpc"
+{{- if $root.NeedContext}}
+import "context"
+{{- end}}
 {{- range $_, $im := $root.Imports}}
 import "{{$im}}"
 {{- end}}
@@ -23,14 +50,14 @@ import "{{$im}}"
 type I{{$m.Name}} interface {
     {{- range $_, $method := $m.Methods}}
     {{$method.Name -}}(
-	{{- range $index, $arg := $method.Args}}
+	{{- range $index, $arg := $method.HandlerArgs}}
 		{{- $arg -}}
-		{{if ne $index (sub (len $method.Args) 1)}}, {{end}}
+		{{if ne $index (sub (len $method.HandlerArgs) 1)}}, {{end}}
 	{{- end -}})
-    {{- if len $method.Returns }} (
-		{{- range $index, $ret := $method.Returns}}
+    {{- if len $method.HandlerReturns }} (
+		{{- range $index, $ret := $method.HandlerReturns}}
 		{{- $ret -}}
-		{{if ne $index (sub (len $method.Returns) 1)}}, {{end}}
+		{{if ne $index (sub (len $method.HandlerReturns) 1)}}, {{end}}
 		{{- end}})
     {{- end}}
     {{- end}}
@@ -44,6 +71,7 @@ func Register{{$m.Name}}Server(rpc *xrpc.RPCServer, s I{{$m.Name}}) {
 	rpc.Register("{{$method.Name}}", s.{{$method.Name}})
 	{{- end }}
 	{{- end}}
+	rpc.SetServingStatus("{{$m.Name}}", xrpc.Serving)
 }
 
 type {{$m.Name}}Client struct {
@@ -55,8 +83,26 @@ func New{{$m.Name}}Client(c xrpc.IRPCClient) *{{$m.Name}}Client {
 }
 
 {{range $_, $method := $m.Methods}}
-func (c *{{$m.Name}}Client) {{$method.Name}}(subj string
-	{{- range $index, $arg := $method.Args -}}
+{{- if $method.IsStreaming}}
+func (c *{{$m.Name}}Client) {{$method.Name}}({{if $method.HasContext}}ctx context.Context, {{end}}subj string
+	{{- range $index, $arg := $method.CallArgs -}}
+		, arg{{$index}} {{$arg -}}
+	{{- end -}}) (*{{$method.ClientStream}}, error) {
+	{{- template "prelude" $method}}
+	{{- if $method.HasContext}}
+    stream, err := c.c.NewStream(ctx, subj, "{{$method.Name}}"
+	{{- else}}
+    stream, err := c.c.NewStream(context.Background(), subj, "{{$method.Name}}"
+	{{- end}}
+	{{- template "args" $method -}})
+    if err != nil {
+        return nil, err
+    }
+    return &{{$method.ClientStream}}{ClientStream: stream}, nil
+}
+{{- else}}
+func (c *{{$m.Name}}Client) {{$method.Name}}({{if $method.HasContext}}ctx context.Context, {{end}}subj string
+	{{- range $index, $arg := $method.CallArgs -}}
 		, arg{{$index}} {{$arg -}}
 	{{- end -}}) 
 	{{- if len $method.Returns }} (
@@ -66,27 +112,75 @@ func (c *{{$m.Name}}Client) {{$method.Name}}(subj string
 		{{- end}})
 	{{- else}} error
 	{{- end}} {
+	{{- template "prelude" $method}}
 
-	{{- if len $method.Returns }}
+	{{- if eq (len $method.Replies) 1 }}
     var reply {{decayReply (getReply $method.Returns)}}
+	{{- if $method.HasContext}}
+    err := c.c.CallContext(ctx, subj, "{{$method.Name}}", &reply
+	{{- else}}
     err := c.c.Call(subj, "{{$method.Name}}", &reply
-    {{- range $index, $arg := $method.Args -}}
-		, arg{{$index}}
-	{{- end -}})
+	{{- end}}
+	{{- template "args" $method -}})
 	{{- if isPointerReply (getReply $method.Returns)}}
     return &reply, err
 	{{- else}}
     return reply, err
 	{{- end}}
 
+	{{- else if len $method.Returns }}
+	{{- range $index, $ret := $method.Replies}}
+    var reply{{$index}} {{decayReply $ret}}
+	{{- end}}
+	{{- if $method.HasContext}}
+    err := c.c.CallContext(ctx, subj, "{{$method.Name}}", 
+	{{- else}}
+    err := c.c.Call(subj, "{{$method.Name}}", 
+	{{- end}}
+	{{- if $method.Replies}} xrpc.Replies{
+		{{- range $index, $_ := $method.Replies}}{{if $index}}, {{end}}&reply{{$index}}{{end -}}
+	}{{else}} nil{{end}}
+	{{- template "args" $method -}})
+    return {{range $index, $ret := $method.Replies}}{{if isPointerReply $ret}}&{{end}}reply{{$index}}, {{end}}err
+
+	{{- else}}
+	{{- if $method.HasContext}}
+    err := c.c.CastContext(ctx, subj, "{{$method.Name}}"
 	{{- else}}
     err := c.c.Cast(subj, "{{$method.Name}}"
-	{{- range $index, $arg := $method.Args -}}
-		, arg{{$index}}
-	{{- end -}})
+	{{- end}}
+	{{- template "args" $method -}})
     return err
 	{{- end}}
 }
+{{- if len $method.Returns }}
+
+func (c *{{$m.Name}}Client) {{$method.Name}}Async({{if $method.HasContext}}ctx context.Context, {{end}}subj string
+	{{- range $index, $arg := $method.CallArgs -}}
+		, arg{{$index}} {{$arg -}}
+	{{- end -}}) *xrpc.Call {
+	{{- template "prelude" $method}}
+	{{- if eq (len $method.Replies) 1 }}
+    var reply {{decayReply (getReply $method.Returns)}}
+	{{- if $method.HasContext}}
+    return c.c.GoContext(ctx, subj, "{{$method.Name}}", &reply
+	{{- else}}
+    return c.c.Go(subj, "{{$method.Name}}", &reply
+	{{- end}}
+	{{- else}}
+	{{- if $method.HasContext}}
+    return c.c.GoContext(ctx, subj, "{{$method.Name}}", 
+	{{- else}}
+    return c.c.Go(subj, "{{$method.Name}}", 
+	{{- end}}
+	{{- if $method.Replies}} xrpc.Replies{
+		{{- range $index, $ret := $method.Replies}}{{if $index}}, {{end}}new({{decayReply $ret}}){{end -}}
+	}{{else}} nil{{end}}
+	{{- end}}
+	{{- template "args" $method -}})
+}
+{{- end}}
+{{- end}}
 {{end}}
 {{- end -}}
 `
@@ -119,6 +213,7 @@ func generate(ast *PackageAST, file *os.File) error {
 		},
 	}
 	t := template.Must(template.New("").Funcs(funcs).Parse(tmplService))
+	template.Must(t.Parse(tmplArgs))
 
 	return t.Execute(file, ast)
 }
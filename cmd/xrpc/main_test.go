@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const variadicService = `package main
+
+service Calc {
+    Sum(int, ...int) (int, error)
+    go Join(string, ...string) (string, error)
+    Count(...string) (int, error)
+}
+`
+
+const variadicMain = `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yc90s/xrpc"
+	memorymq "github.com/yc90s/xrpc/mq/memory"
+)
+
+type calc struct{}
+
+func (calc) Sum(base int, nums ...int) (int, error) {
+	for _, n := range nums {
+		base += n
+	}
+	return base, nil
+}
+
+func (calc) Join(sep string, parts ...string) (string, error) {
+	return strings.Join(parts, sep), nil
+}
+
+func (calc) Count(parts ...string) (int, error) {
+	return len(parts), nil
+}
+
+func check(got any, err error, want any) {
+	if err != nil || got != want {
+		fmt.Printf("got:%v err:%v, want:%v\n", got, err, want)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	q := memorymq.NewMQueen(memorymq.NewBroker())
+	s := xrpc.NewRPCServer(xrpc.SetMQ(q), xrpc.SetSubj("calc"))
+	RegisterCalcServer(s, calc{})
+	if err := s.Start(); err != nil {
+		panic(err)
+	}
+	defer s.Stop()
+	c := xrpc.NewRPCClient(xrpc.SetMQ(q), xrpc.SetSubj("calc_client"))
+	defer c.Close()
+
+	client := NewCalcClient(c)
+	sum, err := client.Sum("calc", 10)
+	check(sum, err, 10)
+	sum, err = client.Sum("calc", 10, 1, 2)
+	check(sum, err, 13)
+	sum, err = client.Sum("calc", 10, []int{1, 2, 3}...)
+	check(sum, err, 16)
+
+	joined, err := client.Join("calc", "-", "a", "b", "c")
+	check(joined, err, "a-b-c")
+	call := client.JoinAsync("calc", ",", "x", "y")
+	check(*call.Reply.(*string), call.Wait(), "x,y")
+
+	n, err := client.Count("calc")
+	check(n, err, 0)
+	n, err = client.Count("calc", "a", "b")
+	check(n, err, 2)
+}
+`
+
+func Test_Generate_variadic(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	ast, err := NewParser(NewLexer([]byte(variadicService))).parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// generated in the module to import xrpc, the leading "_" keeps it out of ./...
+	dir, err := os.MkdirTemp(".", "_variadic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := os.Create(filepath.Join(dir, "calc.service.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = generate(ast, file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(variadicMain), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(goBin, "run", "./"+dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 )
 
 type AST interface {
@@ -19,10 +20,13 @@ type ServiceAST struct {
 }
 
 type MethodAST struct {
-	Name    string
-	Args    []string
-	Returns []string
-	IsGo    bool
+	Name       string
+	Args       []string
+	Returns    []string
+	IsGo       bool
+	HasContext bool   // the first arg is context.Context
+	Stream     string // message type sent by the streaming method, empty if it doesn't send
+	ArgStream  string // message type received by the streaming method, empty if it doesn't receive
 }
 
 func (s *ServiceAST) String() string {
@@ -42,14 +46,17 @@ func (m *MethodAST) String() string {
 // serviceList: service | service serviceList
 // service: SERVICE ID LCURLY serviceMethodList RCURLY
 // serviceMethodList: serviceMethodDecl serviceMethodList | serviceMethodDecl
-// serviceMethodDecl: (GO)+ ID LPAREN formalParametersList RPAREN returnValue
+// serviceMethodDecl: (GO)+ ID LPAREN methodParametersList RPAREN (returnValue | STREAM parameter)
+// methodParametersList: parametersList
+//                     | parametersList COMMA STREAM parameter
+//                     | STREAM parameter
 // parametersList: parameter
 //    			 | parameter COMMA parametersList
 //               | empty
 // parameter: ID
 // PATH: ".+"
 // ID: (*)?([])?[a-zA-Z_][a-zA-Z0-9_]*
-// returnValue: empty | LPAREN parameter COMMA parameter RPAREN
+// returnValue: empty | ERROR | LPAREN parametersList RPAREN, the last parameter is ERROR
 
 type Parser struct {
 	lexer        *Lexer
@@ -73,13 +80,91 @@ func NewServiceAST(name string, methods []*MethodAST) *ServiceAST {
 
 func NewMethodAST(name string, args, returns []string, isGo bool) *MethodAST {
 	return &MethodAST{
-		Name:    name,
-		Args:    args,
-		Returns: returns,
-		IsGo:    isGo,
+		Name:       name,
+		Args:       args,
+		Returns:    returns,
+		IsGo:       isGo,
+		HasContext: len(args) > 0 && args[0] == CONTEXT,
 	}
 }
 
+// IsStreaming reports whether the method sends or receives a stream
+func (m *MethodAST) IsStreaming() bool {
+	return m.Stream != "" || m.ArgStream != ""
+}
+
+// HandlerArgs returns the args of the server method, the stream is the last one
+func (m *MethodAST) HandlerArgs() []string {
+	switch {
+	case m.Stream != "" && m.ArgStream != "":
+		return append(append([]string{}, m.Args...), "*xrpc.BidiStream["+m.ArgStream+", "+m.Stream+"]")
+	case m.Stream != "":
+		return append(append([]string{}, m.Args...), "*xrpc.SendStream["+m.Stream+"]")
+	case m.ArgStream != "":
+		return append(append([]string{}, m.Args...), "*xrpc.RecvStream["+m.ArgStream+"]")
+	}
+	return m.Args
+}
+
+// HandlerReturns returns the return values of the server method,
+// the server-streaming method returns only error, and so does the client-streaming method without reply
+func (m *MethodAST) HandlerReturns() []string {
+	if m.Stream != "" || (m.ArgStream != "" && len(m.Returns) == 0) {
+		return []string{ERROR}
+	}
+	return m.Returns
+}
+
+// ClientStream returns the stream type returned by the client method
+func (m *MethodAST) ClientStream() string {
+	switch {
+	case m.Stream != "" && m.ArgStream != "":
+		return "xrpc.StreamReadWriter[" + m.ArgStream + ", " + m.Stream + "]"
+	case m.ArgStream != "":
+		return "xrpc.StreamWriter[" + m.ArgStream + "]"
+	}
+	return "xrpc.StreamReader[" + m.Stream + "]"
+}
+
+// NeedContext reports whether the generated code needs to import context
+func (p *PackageAST) NeedContext() bool {
+	for _, im := range p.Imports {
+		if im == "context" {
+			return false
+		}
+	}
+	for _, service := range p.Services {
+		for _, method := range service.Methods {
+			if method.IsStreaming() && !method.HasContext {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Replies returns the return values except the trailing error
+func (m *MethodAST) Replies() []string {
+	if len(m.Returns) == 0 {
+		return nil
+	}
+	return m.Returns[:len(m.Returns)-1]
+}
+
+// CallArgs returns the args which need to be sent, context.Context is excluded
+func (m *MethodAST) CallArgs() []string {
+	if m.HasContext {
+		return m.Args[1:]
+	}
+	return m.Args
+}
+
+// IsVariadic reports whether the last arg to be sent is variadic, e.g. ...int
+func (m *MethodAST) IsVariadic() bool {
+	args := m.CallArgs()
+	return len(args) > 0 && strings.HasPrefix(args[len(args)-1], "...")
+}
+
 func NewParser(lexer *Lexer) *Parser {
 	parser := &Parser{
 		lexer:        lexer,
@@ -235,7 +320,7 @@ func (p *Parser) serviceMethodDecl() (*MethodAST, error) {
 		return nil, err
 	}
 
-	args, err := p.formalParametersList()
+	args, argStream, err := p.methodParametersList()
 	if err != nil {
 		return nil, err
 	}
@@ -245,12 +330,70 @@ func (p *Parser) serviceMethodDecl() (*MethodAST, error) {
 		return nil, err
 	}
 
+	// server-streaming method
+	if p.currentToken.tp == STREAM {
+		err = p.eat(STREAM)
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err := p.parameter()
+		if err != nil {
+			return nil, err
+		}
+
+		method := NewMethodAST(methodName, args, nil, isGo)
+		method.Stream = stream
+		method.ArgStream = argStream
+		return method, nil
+	}
+
 	returns, err := p.returnValue()
 	if err != nil {
 		return nil, err
 	}
 
-	return NewMethodAST(methodName, args, returns, isGo), nil
+	method := NewMethodAST(methodName, args, returns, isGo)
+	method.ArgStream = argStream
+	return method, nil
+}
+
+// methodParametersList parses the args of the method, the last one can be the stream received by the method
+func (p *Parser) methodParametersList() ([]string, string, error) {
+	args := make([]string, 0)
+
+	for p.currentToken.tp != RPAREN {
+		if len(args) > 0 {
+			err := p.eat(COMMA)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		if p.currentToken.tp == STREAM {
+			err := p.eat(STREAM)
+			if err != nil {
+				return nil, "", err
+			}
+
+			stream, err := p.parameter()
+			if err != nil {
+				return nil, "", err
+			}
+			if p.currentToken.tp != RPAREN {
+				return nil, "", fmt.Errorf("the stream must be the last arg -> %s", p.currentToken.String())
+			}
+			return args, stream, nil
+		}
+
+		arg, err := p.parameter()
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, arg)
+	}
+
+	return args, "", nil
 }
 
 func (p *Parser) formalParametersList() ([]string, error) {
@@ -292,6 +435,15 @@ func (p *Parser) parameter() (string, error) {
 }
 
 func (p *Parser) returnValue() ([]string, error) {
+	// only error
+	if p.currentToken.tp == ID && p.currentToken.value == ERROR {
+		err := p.eat(ID)
+		if err != nil {
+			return nil, err
+		}
+		return []string{ERROR}, nil
+	}
+
 	// no returns
 	if p.currentToken.tp != LPAREN {
 		return nil, nil
@@ -302,18 +454,14 @@ func (p *Parser) returnValue() ([]string, error) {
 		return nil, err
 	}
 
-	returns := make([]string, 0)
-	param, err := p.parameter()
+	token := p.currentToken
+	returns, err := p.formalParametersList()
 	if err != nil {
 		return nil, err
 	}
-	returns = append(returns, param)
-	p.eat(COMMA)
-	param, err = p.parameter()
-	if err != nil {
-		return nil, err
+	if len(returns) == 0 || returns[len(returns)-1] != ERROR {
+		return nil, fmt.Errorf("the last return value must be error -> %s", token.String())
 	}
-	returns = append(returns, param)
 
 	err = p.eat(RPAREN)
 	if err != nil {
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_Parser_returnValue(t *testing.T) {
+	testData := map[string]string{
+		"Ping()":                              "[]",
+		"Check(bool) error":                   "[error]",
+		"Hello(string) (string, error)":       "[string error]",
+		"DivMod(int, int) (int, *int, error)": "[int *int error]",
+	}
+
+	for method, returns := range testData {
+		parser := NewParser(NewLexer([]byte("package main service S {" + method + "}")))
+		ast, err := parser.parse()
+		if err != nil {
+			t.Fatalf("parse %s: %v", method, err)
+		}
+		if got := fmt.Sprint(ast.Services[0].Methods[0].Returns); got != returns {
+			t.Errorf("%s returns %s != %s", method, got, returns)
+		}
+	}
+
+	parser := NewParser(NewLexer([]byte("package main service S { Hello(string) (string, int) }")))
+	if _, err := parser.parse(); err == nil {
+		t.Error("the last return value must be error")
+	}
+}
+
+func Test_Parser_stream(t *testing.T) {
+	parser := NewParser(NewLexer([]byte("package main service S { go List(string) stream *pb.Item }")))
+	ast, err := parser.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	method := ast.Services[0].Methods[0]
+	if method.Stream != "*pb.Item" || !method.IsGo {
+		t.Errorf("method:%v", method)
+	}
+	if got := fmt.Sprint(method.HandlerArgs(), method.HandlerReturns()); got != "[string *xrpc.SendStream[*pb.Item]] [error]" {
+		t.Errorf("handler:%s", got)
+	}
+	if !ast.NeedContext() {
+		t.Error("stream client without context.Context needs to import context")
+	}
+}
+
+func Test_Parser_argStream(t *testing.T) {
+	parser := NewParser(NewLexer([]byte(`package main service S {
+		Upload(string, stream []byte) (int, error)
+		go Chat(stream *pb.Msg) stream *pb.Msg
+	}`)))
+	ast, err := parser.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := ast.Services[0].Methods[0]
+	if got := fmt.Sprint(upload.HandlerArgs(), upload.HandlerReturns(), " ", upload.ClientStream()); got != "[string *xrpc.RecvStream[[]byte]] [int error] xrpc.StreamWriter[[]byte]" {
+		t.Errorf("upload:%s", got)
+	}
+	chat := ast.Services[0].Methods[1]
+	if got := fmt.Sprint(chat.HandlerArgs(), chat.HandlerReturns(), " ", chat.ClientStream()); got != "[*xrpc.BidiStream[*pb.Msg, *pb.Msg]] [error] xrpc.StreamReadWriter[*pb.Msg, *pb.Msg]" {
+		t.Errorf("chat:%s", got)
+	}
+
+	parser = NewParser(NewLexer([]byte("package main service S { Upload(stream []byte, string) error }")))
+	if _, err := parser.parse(); err == nil {
+		t.Error("the stream must be the last arg")
+	}
+}
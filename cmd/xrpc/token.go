@@ -13,6 +13,10 @@ const (
 	PACKAGE = "package"
 	IMPORT  = "import"
 	GO      = "go"
+	STREAM  = "stream"
+
+	CONTEXT = "context.Context"
+	ERROR   = "error"
 
 	ID   = "ID"
 	PATH = "PATH"
@@ -24,6 +28,7 @@ var RESERVE_KEYWORDS = map[string]string{
 	PACKAGE: PACKAGE,
 	IMPORT:  IMPORT,
 	GO:      GO,
+	STREAM:  STREAM,
 }
 
 var SINGLE_CHAR_TOKENS = map[string]string{
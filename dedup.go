@@ -0,0 +1,89 @@
+package xrpc
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// dedupCache remembers the requests handled by RPCServer by Cid, it is goroutine safe.
+// The entries expire after ttl, and the earliest inserted ones are evicted when the size is exceeded.
+type dedupCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*list.Element
+	fifo    *list.List // in insertion order, the front is the newest
+}
+
+type dedupEntry struct {
+	cid      string
+	expire   time.Time
+	done     bool
+	response []byte // the marshaled response, nil if no reply
+}
+
+func newDedupCache(ttl time.Duration, size int) *dedupCache {
+	return &dedupCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		fifo:    list.New(),
+	}
+}
+
+// begin records the request of cid as handling.
+// If it is a duplicate, begin returns false with whether it's done and its response.
+func (c *dedupCache) begin(cid string) (first bool, done bool, response []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.evict(now)
+
+	if elem, ok := c.entries[cid]; ok {
+		entry := elem.Value.(*dedupEntry)
+		return false, entry.done, entry.response
+	}
+
+	c.entries[cid] = c.fifo.PushFront(&dedupEntry{
+		cid:    cid,
+		expire: now.Add(c.ttl),
+	})
+	return true, false, nil
+}
+
+// finish records the response of cid
+func (c *dedupCache) finish(cid string, response []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[cid]; ok {
+		entry := elem.Value.(*dedupEntry)
+		entry.done = true
+		entry.response = response
+	}
+}
+
+// forget removes cid, so that the request can be handled again
+func (c *dedupCache) forget(cid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[cid]; ok {
+		c.fifo.Remove(elem)
+		delete(c.entries, cid)
+	}
+}
+
+// evict removes the expired entries and the oldest ones exceeding the size
+func (c *dedupCache) evict(now time.Time) {
+	for elem := c.fifo.Back(); elem != nil; elem = c.fifo.Back() {
+		entry := elem.Value.(*dedupEntry)
+		if now.Before(entry.expire) && c.fifo.Len() < c.size {
+			break
+		}
+		c.fifo.Remove(elem)
+		delete(c.entries, entry.cid)
+	}
+}
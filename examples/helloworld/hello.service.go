@@ -20,6 +20,7 @@ func RegisterHelloServiceServer(rpc *xrpc.RPCServer, s IHelloService) {
 	rpc.Register("Ping", s.Ping)
 	rpc.Register("Bye", s.Bye)
 	rpc.RegisterGO("Print", s.Print)
+	rpc.SetServingStatus("HelloService", xrpc.Serving)
 }
 
 type HelloServiceClient struct {
@@ -37,18 +38,33 @@ func (c *HelloServiceClient) Hello(subj string, arg0 string) (string, error) {
     return reply, err
 }
 
+func (c *HelloServiceClient) HelloAsync(subj string, arg0 string) *xrpc.Call {
+    var reply string
+    return c.c.Go(subj, "Hello", &reply, arg0)
+}
+
 func (c *HelloServiceClient) HelloError(subj string, arg0 *string, arg1 string) (*string, error) {
     var reply string
     err := c.c.Call(subj, "HelloError", &reply, arg0, arg1)
     return &reply, err
 }
 
+func (c *HelloServiceClient) HelloErrorAsync(subj string, arg0 *string, arg1 string) *xrpc.Call {
+    var reply string
+    return c.c.Go(subj, "HelloError", &reply, arg0, arg1)
+}
+
 func (c *HelloServiceClient) Add(subj string, arg0 int, arg1 *int) (int, error) {
     var reply int
     err := c.c.Call(subj, "Add", &reply, arg0, arg1)
     return reply, err
 }
 
+func (c *HelloServiceClient) AddAsync(subj string, arg0 int, arg1 *int) *xrpc.Call {
+    var reply int
+    return c.c.Go(subj, "Add", &reply, arg0, arg1)
+}
+
 func (c *HelloServiceClient) Ping(subj string) error {
     err := c.c.Cast(subj, "Ping")
     return err
@@ -64,3 +80,8 @@ func (c *HelloServiceClient) Print(subj string, arg0 []byte) ([]byte, error) {
     err := c.c.Call(subj, "Print", &reply, arg0)
     return reply, err
 }
+
+func (c *HelloServiceClient) PrintAsync(subj string, arg0 []byte) *xrpc.Call {
+    var reply []byte
+    return c.c.Go(subj, "Print", &reply, arg0)
+}
@@ -13,6 +13,7 @@ type IHelloService interface {
 func RegisterHelloServiceServer(rpc *xrpc.RPCServer, s IHelloService) {
 	rpc.Register("Hello", s.Hello)
 	rpc.Register("Add", s.Add)
+	rpc.SetServingStatus("HelloService", xrpc.Serving)
 }
 
 type HelloServiceClient struct {
@@ -30,8 +31,18 @@ func (c *HelloServiceClient) Hello(subj string, arg0 *pb.String) (*pb.String, er
     return &reply, err
 }
 
+func (c *HelloServiceClient) HelloAsync(subj string, arg0 *pb.String) *xrpc.Call {
+    var reply pb.String
+    return c.c.Go(subj, "Hello", &reply, arg0)
+}
+
 func (c *HelloServiceClient) Add(subj string, arg0 *pb.String, arg1 *pb.String) (*pb.String, error) {
     var reply pb.String
     err := c.c.Call(subj, "Add", &reply, arg0, arg1)
     return &reply, err
 }
+
+func (c *HelloServiceClient) AddAsync(subj string, arg0 *pb.String, arg1 *pb.String) *xrpc.Call {
+    var reply pb.String
+    return c.c.Go(subj, "Add", &reply, arg0, arg1)
+}
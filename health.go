@@ -0,0 +1,122 @@
+package xrpc
+
+import (
+	"context"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+)
+
+// HealthMethod is the built-in method reporting the health of RPCServer,
+// it takes no arg and replies *xrpcpb.HealthReply.
+const HealthMethod = ReservedPrefix + "health"
+
+type ServingStatus int32
+
+const (
+	ServingUnknown ServingStatus = iota // the service is not registered
+	Serving
+	NotServing
+	Draining // the server is shutting down
+)
+
+func (s ServingStatus) String() string {
+	switch s {
+	case Serving:
+		return "SERVING"
+	case NotServing:
+		return "NOT_SERVING"
+	case Draining:
+		return "DRAINING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Health is the health of a server reported by HealthMethod
+type Health struct {
+	Status       ServingStatus            // the whole server
+	Services     map[string]ServingStatus // each service
+	ExecutingNum int64                    // the number of methods being executed
+}
+
+// SetServingStatus sets the status of service, the empty service means the whole server.
+// All of them are reported as Draining when the server is shutting down.
+// goroutine safe
+func (s *RPCServer) SetServingStatus(service string, status ServingStatus) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if service == "" {
+		s.serving = status
+		return
+	}
+	if s.services == nil {
+		s.services = make(map[string]ServingStatus)
+	}
+	s.services[service] = status
+}
+
+// health implements HealthMethod
+func (s *RPCServer) health() (*xrpcpb.HealthReply, error) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	reply := &xrpcpb.HealthReply{
+		Status:       int32(s.serving),
+		Services:     make(map[string]int32, len(s.services)),
+		ExecutingNum: s.executingNum.Load(),
+	}
+	for service, status := range s.services {
+		reply.Services[service] = int32(status)
+	}
+
+	if s.draining.Load() {
+		reply.Status = int32(Draining)
+		for service := range reply.Services {
+			reply.Services[service] = int32(Draining)
+		}
+	}
+	return reply, nil
+}
+
+// replyHealth replies HealthMethod while the server is draining,
+// it's not counted into the methods Shutdown waits for
+func (s *RPCServer) replyHealth(request *xrpcpb.Request) {
+	reply, _ := s.health()
+	data, err := s.opts.codec.Marshal(reply)
+	if err != nil {
+		s.replyError(request, Errorf(Internal, "marshal reply error: %v", err))
+		return
+	}
+	s.sendResponse(&RPCInfo{
+		request: request,
+		response: &xrpcpb.Response{
+			Cid:    request.Cid,
+			Result: data,
+		},
+		needReply: true,
+	})
+}
+
+// CheckHealth gets the health of the server subscribing subj by calling HealthMethod
+func (c *RPCClient) CheckHealth(subj string) (*Health, error) {
+	return c.CheckHealthContext(context.Background(), subj)
+}
+
+// CheckHealthContext is like CheckHealth, but it gives up when ctx is done
+func (c *RPCClient) CheckHealthContext(ctx context.Context, subj string) (*Health, error) {
+	reply := new(xrpcpb.HealthReply)
+	if err := c.CallContext(ctx, subj, HealthMethod, reply); err != nil {
+		return nil, err
+	}
+
+	health := &Health{
+		Status:       ServingStatus(reply.Status),
+		Services:     make(map[string]ServingStatus, len(reply.Services)),
+		ExecutingNum: reply.ExecutingNum,
+	}
+	for service, status := range reply.Services {
+		health.Services[service] = ServingStatus(status)
+	}
+	return health, nil
+}
@@ -0,0 +1,64 @@
+package xrpc
+
+import "context"
+
+// UnaryHandler invokes the registered method with the decoded args,
+// it returns the results of the method without the trailing error.
+type UnaryHandler func(ctx context.Context, args []any) ([]any, error)
+
+// UnaryServerInterceptor intercepts the method dispatch of RPCServer.
+// header describes the request and args are the decoded args.
+// An interceptor can short-circuit the request by returning an error without calling handler,
+// the error is replied to the caller if the request needs a reply.
+type UnaryServerInterceptor func(ctx context.Context, header *RequestHeader, args []any, handler UnaryHandler) ([]any, error)
+
+// chainServerInterceptors chains interceptors into one, the first one is the outermost.
+// It returns nil if there are no interceptors.
+func chainServerInterceptors(interceptors []UnaryServerInterceptor) UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context, header *RequestHeader, args []any, handler UnaryHandler) ([]any, error) {
+		return interceptors[0](ctx, header, args, chainedHandler(interceptors, 0, header, handler))
+	}
+}
+
+func chainedHandler(interceptors []UnaryServerInterceptor, cur int, header *RequestHeader, final UnaryHandler) UnaryHandler {
+	if cur == len(interceptors)-1 {
+		return final
+	}
+
+	return func(ctx context.Context, args []any) ([]any, error) {
+		return interceptors[cur+1](ctx, header, args, chainedHandler(interceptors, cur+1, header, final))
+	}
+}
+
+// UnaryInvoker sends the request of Call or Cast, reply is nil for Cast.
+type UnaryInvoker func(ctx context.Context, subj string, methodName string, reply any, args []any) error
+
+// UnaryClientInterceptor intercepts the outgoing Call and Cast of RPCClient.
+// reply is nil for Cast, the error returned by invoker is the result of the call.
+type UnaryClientInterceptor func(ctx context.Context, subj string, methodName string, reply any, args []any, invoker UnaryInvoker) error
+
+// chainClientInterceptors chains interceptors into one, the first one is the outermost.
+// It returns nil if there are no interceptors.
+func chainClientInterceptors(interceptors []UnaryClientInterceptor) UnaryClientInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context, subj string, methodName string, reply any, args []any, invoker UnaryInvoker) error {
+		return interceptors[0](ctx, subj, methodName, reply, args, chainedInvoker(interceptors, 0, invoker))
+	}
+}
+
+func chainedInvoker(interceptors []UnaryClientInterceptor, cur int, final UnaryInvoker) UnaryInvoker {
+	if cur == len(interceptors)-1 {
+		return final
+	}
+
+	return func(ctx context.Context, subj string, methodName string, reply any, args []any) error {
+		return interceptors[cur+1](ctx, subj, methodName, reply, args, chainedInvoker(interceptors, cur+1, final))
+	}
+}
@@ -0,0 +1,111 @@
+package xrpc
+
+import "sync"
+
+// OverloadPolicy decides what RPCServer does with a request of the RegisterGO method
+// when the concurrency limit is reached or the worker pool queue is full
+type OverloadPolicy int
+
+const (
+	OverloadBlock  OverloadPolicy = iota // wait until there is room, it blocks the mq callback
+	OverloadReject                       // reply ErrOverloaded to the caller
+	OverloadDrop                         // drop the request without reply
+)
+
+var (
+	ErrOverloaded    error = NewError(ResourceExhausted, "overloaded")
+	ErrServerStopped error = NewError(Unavailable, "server stopped")
+)
+
+// semaphore limits the concurrency, nil means unlimited
+type semaphore chan struct{}
+
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+// acquire takes a slot, it returns false if there is no free slot and block is false
+func (s semaphore) acquire(block bool) bool {
+	if s == nil {
+		return true
+	}
+	if block {
+		s <- struct{}{}
+		return true
+	}
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// workerPool runs the tasks by a fixed number of goroutines, the waiting tasks are queued
+type workerPool struct {
+	mu     sync.RWMutex
+	tasks  chan func()
+	closed bool
+}
+
+func newWorkerPool(workers int, queueSize int) *workerPool {
+	p := &workerPool{
+		tasks: make(chan func(), queueSize),
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// submit queues the task, it returns false if the pool is closed,
+// or the queue is full and block is false
+func (p *workerPool) submit(task func(), block bool) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+
+	if block {
+		p.tasks <- task
+		return true
+	}
+	select {
+	case p.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops accepting tasks, and returns the queued tasks not taken by the workers yet
+func (p *workerPool) close() []func() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.tasks)
+	p.mu.Unlock()
+
+	var queued []func()
+	for task := range p.tasks {
+		queued = append(queued, task)
+	}
+	return queued
+}
@@ -0,0 +1,129 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrNoTrailer = errors.New("trailer is not supported by the context")
+)
+
+// Metadata is the headers of a request or the trailers of a response
+type Metadata map[string]string
+
+// NewMetadata creates a Metadata from key-value pairs, a key without value is ignored
+func NewMetadata(kv ...string) Metadata {
+	md := make(Metadata, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		md[kv[i]] = kv[i+1]
+	}
+	return md
+}
+
+// Get returns the value of key, empty if not exists
+func (md Metadata) Get(key string) string {
+	return md[key]
+}
+
+// Set sets the value of key
+func (md Metadata) Set(key, value string) {
+	md[key] = value
+}
+
+// Copy returns a copy of md
+func (md Metadata) Copy() Metadata {
+	out := make(Metadata, len(md))
+	for k, v := range md {
+		out[k] = v
+	}
+	return out
+}
+
+type outgoingMDKey struct{}
+type incomingMDKey struct{}
+type trailerKey struct{}
+type trailerReceiverKey struct{}
+
+// NewOutgoingContext attaches md to ctx, it will be sent with the Call or Cast using ctx.
+// It replaces the outgoing metadata already in ctx.
+func NewOutgoingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, outgoingMDKey{}, md)
+}
+
+// AppendToOutgoingContext returns a new context with the key-value pairs merged into the outgoing metadata
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromOutgoingContext(ctx)
+	md = md.Copy()
+	for k, v := range NewMetadata(kv...) {
+		md[k] = v
+	}
+	return NewOutgoingContext(ctx, md)
+}
+
+// FromOutgoingContext returns the outgoing metadata in ctx
+func FromOutgoingContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(outgoingMDKey{}).(Metadata)
+	return md, ok
+}
+
+// FromIncomingContext returns the metadata of the request being handled,
+// it is available in server interceptors and methods which take context.Context.
+func FromIncomingContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(incomingMDKey{}).(Metadata)
+	return md, ok
+}
+
+func newIncomingContext(ctx context.Context, md Metadata) context.Context {
+	if md == nil {
+		md = Metadata{}
+	}
+	return context.WithValue(ctx, incomingMDKey{}, md)
+}
+
+// trailer collects the trailers set by the server, it is goroutine safe
+type trailer struct {
+	mu sync.Mutex
+	md Metadata
+}
+
+func newTrailerContext(ctx context.Context) (context.Context, *trailer) {
+	t := &trailer{}
+	return context.WithValue(ctx, trailerKey{}, t), t
+}
+
+func (t *trailer) metadata() Metadata {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.md
+}
+
+// SetTrailer merges md into the trailers of the response,
+// ctx must be the context passed to server interceptors or methods.
+func SetTrailer(ctx context.Context, md Metadata) error {
+	t, ok := ctx.Value(trailerKey{}).(*trailer)
+	if !ok {
+		return ErrNoTrailer
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.md == nil {
+		t.md = make(Metadata, len(md))
+	}
+	for k, v := range md {
+		t.md[k] = v
+	}
+	return nil
+}
+
+// WithTrailer returns a new context, the trailers of the Call using it will be stored in md
+func WithTrailer(ctx context.Context, md *Metadata) context.Context {
+	return context.WithValue(ctx, trailerReceiverKey{}, md)
+}
+
+func trailerReceiver(ctx context.Context) *Metadata {
+	md, _ := ctx.Value(trailerReceiverKey{}).(*Metadata)
+	return md
+}
@@ -0,0 +1,156 @@
+package xrpc
+
+import (
+	"sync"
+	"time"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+
+	"github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+)
+
+// microBatcher coalesces the requests of RPCClient to the same subject, see SetMicroBatch
+type microBatcher struct {
+	c       *RPCClient
+	linger  time.Duration
+	maxSize int
+	mu      sync.Mutex
+	pending map[string]*pendingBatch // subject -> the requests queued
+}
+
+type pendingBatch struct {
+	requests []*queuedRequest
+	timer    *time.Timer
+	flushAt  time.Time
+}
+
+type queuedRequest struct {
+	request  *xrpcpb.Request
+	deadline time.Time // zero if no deadline
+}
+
+func newMicroBatcher(c *RPCClient, linger time.Duration, maxSize int) *microBatcher {
+	if linger <= 0 || maxSize <= 1 {
+		return nil
+	}
+	return &microBatcher{
+		c:       c,
+		linger:  linger,
+		maxSize: maxSize,
+		pending: make(map[string]*pendingBatch),
+	}
+}
+
+// add queues the request, the queue is flushed when it's full or after linger.
+// A request with a deadline sooner than linger flushes the queue in half of its remaining time,
+// so that it isn't timed out by lingering.
+func (b *microBatcher) add(subj string, request *xrpcpb.Request, deadline time.Time) {
+	wait := b.linger
+	if !deadline.IsZero() {
+		if remaining := time.Until(deadline) / 2; remaining < wait {
+			wait = remaining
+		}
+	}
+
+	b.mu.Lock()
+	p, ok := b.pending[subj]
+	if !ok {
+		p = &pendingBatch{flushAt: time.Now().Add(wait)}
+		p.timer = time.AfterFunc(wait, func() {
+			b.expire(subj, p)
+		})
+		b.pending[subj] = p
+	} else if flushAt := time.Now().Add(wait); flushAt.Before(p.flushAt) {
+		p.flushAt = flushAt
+		p.timer.Reset(wait)
+	}
+
+	p.requests = append(p.requests, &queuedRequest{request: request, deadline: deadline})
+	if len(p.requests) < b.maxSize {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, subj)
+	p.timer.Stop()
+	b.mu.Unlock()
+
+	b.publish(subj, p.requests)
+}
+
+// expire flushes the queue after linger, unless it has been flushed
+func (b *microBatcher) expire(subj string, p *pendingBatch) {
+	b.mu.Lock()
+	if b.pending[subj] != p {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, subj)
+	b.mu.Unlock()
+
+	b.publish(subj, p.requests)
+}
+
+// flush sends all the queued requests
+func (b *microBatcher) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]*pendingBatch)
+	b.mu.Unlock()
+
+	for subj, p := range pending {
+		p.timer.Stop()
+		b.publish(subj, p.requests)
+	}
+}
+
+// publish sends the requests in one message, a single request is sent without the envelope.
+// The timeout of each request is counted from now, the requests already expired are dropped.
+// If it fails, the calls waiting for the replies fail with Unavailable.
+func (b *microBatcher) publish(subj string, queued []*queuedRequest) {
+	requests := make([]*xrpcpb.Request, 0, len(queued))
+	for _, q := range queued {
+		if !q.deadline.IsZero() {
+			remaining := time.Until(q.deadline)
+			if remaining <= 0 {
+				// the caller has given up
+				glog.Info("drop expired request: ", q.request.Method)
+				continue
+			}
+			q.request.Timeout = int64(remaining)
+		}
+		requests = append(requests, q.request)
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	request := requests[0]
+	if len(requests) > 1 {
+		request = &xrpcpb.Request{
+			ReplyTo: b.c.opts.subj,
+			Batch:   requests,
+		}
+	}
+
+	data, err := proto.Marshal(request)
+	if err == nil {
+		err = b.c.opts.mq.Publish(subj, data)
+	}
+	if err == nil {
+		return
+	}
+
+	glog.Error("mq.Publish error: ", err)
+	status := toStatus(Errorf(Unavailable, "publish error: %v", err), b.c.opts.codec)
+	for _, request := range requests {
+		if request.Cid == "" {
+			continue
+		}
+		b.c.handleResponse(&xrpcpb.Response{
+			Cid:    request.Cid,
+			Error:  status.Message,
+			Status: status,
+		})
+	}
+}
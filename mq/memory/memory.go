@@ -0,0 +1,234 @@
+package memorymq
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yc90s/xrpc/mq"
+)
+
+var (
+	ErrClosed = errors.New("broker closed")
+)
+
+type options struct {
+	buffer  int
+	ordered bool
+}
+
+type Option func(*options)
+
+// SetBuffer sets the number of messages buffered by each subscription,
+// Publish blocks when the buffer of a subscriber is full. default 1024
+func SetBuffer(n int) Option {
+	return func(o *options) {
+		o.buffer = n
+	}
+}
+
+// SetOrdered sets whether messages of a subscription are handled one by one in publish order.
+// If not ordered, each message is handled in a new goroutine. default true
+func SetOrdered(ordered bool) Option {
+	return func(o *options) {
+		o.ordered = ordered
+	}
+}
+
+// Broker routes messages from Publish to the subscribers of the same subject in process.
+// Subjects are matched exactly, wildcards are not supported.
+type Broker struct {
+	opts   options
+	mu     sync.RWMutex
+	subs   map[string]map[*subscription]struct{}
+	closed bool
+	seq    atomic.Uint64 // to pick the subscriber of a queue group
+}
+
+func NewBroker(opts ...Option) *Broker {
+	b := &Broker{
+		opts: options{
+			buffer:  1024,
+			ordered: true,
+		},
+		subs: make(map[string]map[*subscription]struct{}),
+	}
+	for _, o := range opts {
+		o(&b.opts)
+	}
+	return b
+}
+
+// Publish delivers data to all subscribers of subj, it's dropped if there is no subscriber
+func (b *Broker) Publish(subj string, data []byte) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := make([]*subscription, 0, len(b.subs[subj]))
+	groups := make(map[string][]*subscription)
+	for sub := range b.subs[subj] {
+		if sub.queue == "" {
+			subs = append(subs, sub)
+		} else {
+			groups[sub.queue] = append(groups[sub.queue], sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	// only one subscriber of each queue group receives the message
+	for _, group := range groups {
+		subs = append(subs, group[int(b.seq.Add(1)%uint64(len(group)))])
+	}
+
+	// the publisher may reuse data
+	msg := make([]byte, len(data))
+	copy(msg, data)
+
+	for _, sub := range subs {
+		sub.deliver(msg)
+	}
+	return nil
+}
+
+// Close closes the broker, all subscribers receive ErrClosed
+func (b *Broker) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = make(map[string]map[*subscription]struct{})
+	b.mu.Unlock()
+
+	for _, set := range subs {
+		for sub := range set {
+			sub.stop()
+			go sub.cb.Callback(nil, ErrClosed)
+		}
+	}
+}
+
+func (b *Broker) subscribe(subj string, queue string, cb mq.MQCallback) (*subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &subscription{
+		broker:  b,
+		subj:    subj,
+		queue:   queue,
+		cb:      cb,
+		ordered: b.opts.ordered,
+		msgs:    make(chan []byte, b.opts.buffer),
+		done:    make(chan struct{}),
+	}
+	if b.subs[subj] == nil {
+		b.subs[subj] = make(map[*subscription]struct{})
+	}
+	b.subs[subj][sub] = struct{}{}
+
+	go sub.run()
+	return sub, nil
+}
+
+type subscription struct {
+	broker   *Broker
+	subj     string
+	queue    string
+	cb       mq.MQCallback
+	ordered  bool
+	msgs     chan []byte
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Unsubscribe stops the delivery, the messages being handled are not waited
+func (s *subscription) Unsubscribe() error {
+	b := s.broker
+	b.mu.Lock()
+	set, ok := b.subs[s.subj]
+	if ok {
+		_, ok = set[s]
+	}
+	if !ok {
+		b.mu.Unlock()
+		return errors.New("not subscribed")
+	}
+	delete(set, s)
+	if len(set) == 0 {
+		delete(b.subs, s.subj)
+	}
+	b.mu.Unlock()
+
+	s.stop()
+	return nil
+}
+
+func (s *subscription) deliver(data []byte) {
+	select {
+	case s.msgs <- data:
+	case <-s.done:
+	}
+}
+
+func (s *subscription) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *subscription) run() {
+	for {
+		select {
+		case data := <-s.msgs:
+			// don't deliver any more after stopped
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+
+			if s.ordered {
+				s.cb.Callback(data, nil)
+			} else {
+				go s.cb.Callback(data, nil)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// MQueen is a mq.MQueen on a Broker, it can hold many subscriptions
+type MQueen struct {
+	broker *Broker
+}
+
+func NewMQueen(b *Broker) *MQueen {
+	return &MQueen{
+		broker: b,
+	}
+}
+
+func (q *MQueen) Publish(subj string, data []byte) error {
+	return q.broker.Publish(subj, data)
+}
+
+func (q *MQueen) Subscribe(subj string, cb mq.MQCallback) (mq.Subscription, error) {
+	return q.broker.subscribe(subj, "", cb)
+}
+
+// QueueSubscribe subscribes subj in the queue group,
+// each message is delivered to only one subscription of the group.
+func (q *MQueen) QueueSubscribe(subj string, queue string, cb mq.MQCallback) (mq.Subscription, error) {
+	if queue == "" {
+		return nil, errors.New("empty queue group")
+	}
+	return q.broker.subscribe(subj, queue, cb)
+}
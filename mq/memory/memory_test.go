@@ -0,0 +1,135 @@
+package memorymq
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yc90s/xrpc/mq"
+	"github.com/yc90s/xrpc/mq/mqtest"
+)
+
+type recorder struct {
+	mu   sync.Mutex
+	msgs []string
+	errs []error
+	ch   chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{ch: make(chan struct{}, 1024)}
+}
+
+func (r *recorder) Callback(data []byte, err error) {
+	r.mu.Lock()
+	if err != nil {
+		r.errs = append(r.errs, err)
+	} else {
+		r.msgs = append(r.msgs, string(data))
+	}
+	r.mu.Unlock()
+	r.ch <- struct{}{}
+}
+
+func (r *recorder) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.ch:
+		case <-time.After(time.Second):
+			t.Fatalf("wait message %d timeout", i)
+		}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b := NewBroker()
+	r1, r2 := newRecorder(), newRecorder()
+	q := NewMQueen(b)
+
+	if _, err := q.Subscribe("hello", r1); err != nil {
+		t.Fatal(err)
+	}
+	sub2, err := q.Subscribe("hello", r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range []string{"a", "b", "c"} {
+		if err := q.Publish("hello", []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r1.wait(t, 3)
+	r2.wait(t, 3)
+
+	for _, r := range []*recorder{r1, r2} {
+		if len(r.msgs) != 3 || r.msgs[0] != "a" || r.msgs[1] != "b" || r.msgs[2] != "c" {
+			t.Errorf("msgs:%v not in order", r.msgs)
+		}
+	}
+
+	if err := sub2.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub2.Unsubscribe(); err == nil {
+		t.Error("unsubscribe twice should fail")
+	}
+
+	q.Publish("hello", []byte("d"))
+	r1.wait(t, 1)
+	time.Sleep(10 * time.Millisecond)
+	if len(r2.msgs) != 3 {
+		t.Errorf("unsubscribed but received: %v", r2.msgs)
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	b := NewBroker(SetOrdered(false), SetBuffer(0))
+	r := newRecorder()
+	q := NewMQueen(b)
+	if _, err := q.Subscribe("hello", r); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		q.Publish("hello", []byte("x"))
+	}
+	r.wait(t, 100)
+}
+
+func TestClose(t *testing.T) {
+	b := NewBroker()
+	r := newRecorder()
+	q := NewMQueen(b)
+	if _, err := q.Subscribe("hello", r); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Close()
+	r.wait(t, 1)
+	if len(r.errs) != 1 || r.errs[0] != ErrClosed {
+		t.Errorf("errs:%v != ErrClosed", r.errs)
+	}
+	if err := q.Publish("hello", []byte("x")); err != ErrClosed {
+		t.Errorf("publish after close: %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	b := NewBroker()
+	mqtest.RunConformance(t, func(t *testing.T) mq.MQueen {
+		return NewMQueen(b)
+	}, mqtest.WithTransport(func(t *testing.T) (mqtest.Factory, func()) {
+		b := NewBroker()
+		return func(t *testing.T) mq.MQueen {
+			return NewMQueen(b)
+		}, b.Close
+	}))
+}
+
+func TestConformanceUnordered(t *testing.T) {
+	b := NewBroker(SetOrdered(false))
+	mqtest.RunConformance(t, func(t *testing.T) mq.MQueen {
+		return NewMQueen(b)
+	}, mqtest.Unordered())
+}
@@ -2,16 +2,39 @@ package mq
 
 // MQCallback is the interface that wraps the basic method of a message queue callback.
 type MQCallback interface {
-	// Callback is the callback function of handle message or error
+	// Callback is the callback function of handle message or error.
+	// It is called with (data, nil) for every message and (nil, err) when the subscription is broken.
+	// It may be called concurrently, so it must be goroutine safe.
 	Callback([]byte, error)
 }
 
+// Subscription is the handle of a subscription returned by MQueen.
+type Subscription interface {
+	// Unsubscribe unsubscribes the subject, it returns an error if already unsubscribed.
+	// No message is delivered after it returns, except the ones being handled.
+	Unsubscribe() error
+}
+
 // MQServer is the interface that wraps the basic method of a message queue server.
+//
+// An implementation must guarantee:
+//   - every subscription of a subject receives the messages published to it after subscribing
+//   - a MQueen can hold many subscriptions at the same time, even of the same subject
+//   - the published data is owned by the message queue, the publisher can reuse it after Publish returns
+//   - Publish without subscribers is not an error
+//
+// mqtest.RunConformance checks an implementation against the contract.
 type MQueen interface {
 	// Publish publishes a message to the subject.
 	Publish(string, []byte) error
-	// Subscribe subscribes a subject.
-	Subscribe(string, MQCallback) error
-	// UnSubscribe unsubscribes a subject.
-	UnSubscribe() error
+	// Subscribe subscribes a subject, the returned Subscription is used to unsubscribe it.
+	Subscribe(string, MQCallback) (Subscription, error)
+}
+
+// QueueSubscriber is implemented by message queues which support queue groups.
+// Each message is delivered to only one of the subscriptions in the same queue group,
+// while subscriptions without queue group still receive every message.
+type QueueSubscriber interface {
+	// QueueSubscribe subscribes a subject in the queue group.
+	QueueSubscribe(subj string, queue string, cb MQCallback) (Subscription, error)
 }
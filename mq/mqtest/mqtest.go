@@ -0,0 +1,476 @@
+// Package mqtest provides a conformance test suite for mq.MQueen implementations.
+package mqtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yc90s/xrpc/mq"
+)
+
+// Factory returns a new MQueen, all MQueens returned by one Factory must be on the same transport
+type Factory func(t *testing.T) mq.MQueen
+
+// Transport creates a new transport, e.g. a broker or a connection, only used by the checks breaking it.
+// It returns the Factory of the MQueens on the transport and the function breaking it,
+// after which every subscription must receive Callback(nil, err).
+type Transport func(t *testing.T) (Factory, func())
+
+type options struct {
+	transport Transport
+	unordered bool
+}
+
+type Option func(*options)
+
+// WithTransport enables the checks of the error delivery
+func WithTransport(transport Transport) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// Unordered skips the checks of the ordered delivery,
+// for the MQueen handling the messages of a subscription concurrently
+func Unordered() Option {
+	return func(o *options) {
+		o.unordered = true
+	}
+}
+
+// WaitTimeout is the time to wait for a message to be delivered
+var WaitTimeout = 2 * time.Second
+
+// settleTime is the time to wait before checking nothing is delivered
+const settleTime = 100 * time.Millisecond
+
+var subjSeq atomic.Int64
+
+// uniqueSubj returns a subject not used by other tests, so that the suite can run on a shared server
+func uniqueSubj(t *testing.T) string {
+	name := strings.NewReplacer("/", ".", " ", "_").Replace(t.Name())
+	return fmt.Sprintf("mqtest.%d.%d.%s", time.Now().UnixNano(), subjSeq.Add(1), name)
+}
+
+// recorder is a goroutine safe MQCallback recording all messages and errors.
+// The test fails if an error is received unless expectErrs is set.
+type recorder struct {
+	t          *testing.T
+	mu         sync.Mutex
+	msgs       [][]byte
+	errs       []error
+	received   chan struct{}
+	expectErrs bool
+	running    atomic.Int32 // the callbacks being called
+	concurrent atomic.Bool  // the callbacks were called concurrently
+}
+
+func newRecorder(t *testing.T) *recorder {
+	r := &recorder{
+		t:        t,
+		received: make(chan struct{}, 4096),
+	}
+	t.Cleanup(func() {
+		if errs := r.errors(); !r.expectErrs && len(errs) > 0 {
+			t.Errorf("unexpected errors: %v", errs)
+		}
+	})
+	return r
+}
+
+func (r *recorder) Callback(data []byte, err error) {
+	if r.running.Add(1) > 1 {
+		r.concurrent.Store(true)
+	}
+	defer r.running.Add(-1)
+
+	r.mu.Lock()
+	if err != nil {
+		if data != nil {
+			r.t.Errorf("callback with both data and error: %v", err)
+		}
+		r.errs = append(r.errs, err)
+	} else {
+		r.msgs = append(r.msgs, data)
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.received <- struct{}{}:
+	default:
+	}
+}
+
+// wait waits until n messages or errors are received
+func (r *recorder) wait(n int) bool {
+	deadline := time.After(WaitTimeout)
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-deadline:
+			return false
+		}
+	}
+	return true
+}
+
+func (r *recorder) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msgs := make([]string, len(r.msgs))
+	for i, m := range r.msgs {
+		msgs[i] = string(m)
+	}
+	return msgs
+}
+
+func (r *recorder) errors() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.errs...)
+}
+
+func subscribe(t *testing.T, q mq.MQueen, subj string, cb mq.MQCallback) mq.Subscription {
+	t.Helper()
+	sub, err := q.Subscribe(subj, cb)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	t.Cleanup(func() {
+		sub.Unsubscribe()
+	})
+	return sub
+}
+
+// RunConformance runs the conformance test suite against the MQueens created by factory
+func RunConformance(t *testing.T, factory Factory, opts ...Option) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	tests := []struct {
+		name string
+		fn   func(*testing.T, Factory)
+	}{
+		{"PublishSubscribe", testPublishSubscribe},
+		{"PublishWithoutSubscriber", testPublishWithoutSubscriber},
+		{"MultipleSubscribers", testMultipleSubscribers},
+		{"ManySubscriptions", testManySubscriptions},
+		{"UnsubscribeTwice", testUnsubscribeTwice},
+		{"UnsubscribeStopsDelivery", testUnsubscribeStopsDelivery},
+		{"Resubscribe", testResubscribe},
+		{"DataOwnership", testDataOwnership},
+		{"ConcurrentPublish", testConcurrentPublish},
+		{"QueueGroup", testQueueGroup},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, factory)
+		})
+	}
+
+	t.Run("OrderedDelivery", func(t *testing.T) {
+		if o.unordered {
+			t.Skip("unordered delivery")
+		}
+		testOrderedDelivery(t, factory)
+	})
+	t.Run("ErrorDelivery", func(t *testing.T) {
+		if o.transport == nil {
+			t.Skip("no transport to break")
+		}
+		testErrorDelivery(t, o.transport)
+	})
+}
+
+func testPublishSubscribe(t *testing.T, factory Factory) {
+	subj := uniqueSubj(t)
+	pub, sub := factory(t), factory(t)
+	r := newRecorder(t)
+	subscribe(t, sub, subj, r)
+
+	if err := pub.Publish(subj, []byte("hello")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if !r.wait(1) {
+		t.Fatal("message not delivered")
+	}
+	if msgs := r.messages(); len(msgs) != 1 || msgs[0] != "hello" {
+		t.Errorf("messages:%v != [hello]", msgs)
+	}
+}
+
+func testPublishWithoutSubscriber(t *testing.T, factory Factory) {
+	if err := factory(t).Publish(uniqueSubj(t), []byte("hello")); err != nil {
+		t.Errorf("Publish without subscriber: %v", err)
+	}
+}
+
+func testMultipleSubscribers(t *testing.T, factory Factory) {
+	subj := uniqueSubj(t)
+	pub := factory(t)
+	r1, r2 := newRecorder(t), newRecorder(t)
+	subscribe(t, factory(t), subj, r1)
+	subscribe(t, factory(t), subj, r2)
+
+	if err := pub.Publish(subj, []byte("hello")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if !r1.wait(1) || !r2.wait(1) {
+		t.Fatal("every subscriber should receive the message")
+	}
+}
+
+// testManySubscriptions checks a MQueen can hold many subscriptions, and they are independent
+func testManySubscriptions(t *testing.T, factory Factory) {
+	subj1, subj2 := uniqueSubj(t), uniqueSubj(t)
+	q := factory(t)
+	r1, r2, r3 := newRecorder(t), newRecorder(t), newRecorder(t)
+	subscribe(t, q, subj1, r1)
+	subscribe(t, q, subj2, r2)
+	sub3 := subscribe(t, q, subj1, r3)
+
+	q.Publish(subj1, []byte("one"))
+	q.Publish(subj2, []byte("two"))
+	if !r1.wait(1) || !r2.wait(1) || !r3.wait(1) {
+		t.Fatal("message not delivered")
+	}
+	if msgs := r1.messages(); len(msgs) != 1 || msgs[0] != "one" {
+		t.Errorf("messages:%v != [one]", msgs)
+	}
+	if msgs := r2.messages(); len(msgs) != 1 || msgs[0] != "two" {
+		t.Errorf("messages:%v != [two]", msgs)
+	}
+
+	// unsubscribe one doesn't affect the others of the same subject
+	if err := sub3.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	q.Publish(subj1, []byte("three"))
+	if !r1.wait(1) {
+		t.Fatal("message not delivered after another subscription unsubscribed")
+	}
+}
+
+func testUnsubscribeTwice(t *testing.T, factory Factory) {
+	sub, err := factory(t).Subscribe(uniqueSubj(t), newRecorder(t))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if err := sub.Unsubscribe(); err == nil {
+		t.Error("Unsubscribe twice should return an error")
+	}
+}
+
+func testUnsubscribeStopsDelivery(t *testing.T, factory Factory) {
+	subj := uniqueSubj(t)
+	pub := factory(t)
+	r := newRecorder(t)
+	sub, err := factory(t).Subscribe(subj, r)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	pub.Publish(subj, []byte("before"))
+	if !r.wait(1) {
+		t.Fatal("message not delivered")
+	}
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	pub.Publish(subj, []byte("after"))
+	time.Sleep(settleTime)
+
+	if msgs := r.messages(); len(msgs) != 1 {
+		t.Errorf("messages:%v delivered after Unsubscribe", msgs)
+	}
+}
+
+func testResubscribe(t *testing.T, factory Factory) {
+	subj := uniqueSubj(t)
+	pub, q := factory(t), factory(t)
+	sub, err := q.Subscribe(subj, newRecorder(t))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+
+	r := newRecorder(t)
+	subscribe(t, q, subj, r)
+	pub.Publish(subj, []byte("hello"))
+	if !r.wait(1) {
+		t.Fatal("message not delivered after re-subscribe")
+	}
+}
+
+func testDataOwnership(t *testing.T, factory Factory) {
+	subj := uniqueSubj(t)
+	pub, sub := factory(t), factory(t)
+	r := newRecorder(t)
+	subscribe(t, sub, subj, r)
+
+	data := []byte("hello")
+	pub.Publish(subj, data)
+	copy(data, "xxxxx")
+	if !r.wait(1) {
+		t.Fatal("message not delivered")
+	}
+	if msgs := r.messages(); msgs[0] != "hello" {
+		t.Errorf("message:%s changed by the publisher", msgs[0])
+	}
+}
+
+func testConcurrentPublish(t *testing.T, factory Factory) {
+	const publishers, count = 8, 100
+
+	subj := uniqueSubj(t)
+	pub, sub := factory(t), factory(t)
+	r := newRecorder(t)
+	subscribe(t, sub, subj, r)
+
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				if err := pub.Publish(subj, []byte(fmt.Sprintf("%d-%d", i, j))); err != nil {
+					t.Errorf("Publish: %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !r.wait(publishers * count) {
+		t.Fatalf("received %d of %d messages", len(r.messages()), publishers*count)
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range r.messages() {
+		if seen[m] {
+			t.Errorf("message:%s delivered twice", m)
+		}
+		seen[m] = true
+	}
+}
+
+// testQueueGroup runs only if the MQueen implements mq.QueueSubscriber
+func testQueueGroup(t *testing.T, factory Factory) {
+	const count = 100
+
+	pub := factory(t)
+	q1, q2, q3 := factory(t), factory(t), factory(t)
+	qs1, ok1 := q1.(mq.QueueSubscriber)
+	qs2, ok2 := q2.(mq.QueueSubscriber)
+	if !ok1 || !ok2 {
+		t.Skip("queue group not supported")
+	}
+
+	subj := uniqueSubj(t)
+	r1, r2, all := newRecorder(t), newRecorder(t), newRecorder(t)
+	for _, s := range []struct {
+		q mq.QueueSubscriber
+		r *recorder
+	}{{qs1, r1}, {qs2, r2}} {
+		sub, err := s.q.QueueSubscribe(subj, "group", s.r)
+		if err != nil {
+			t.Fatalf("QueueSubscribe: %v", err)
+		}
+		t.Cleanup(func() {
+			sub.Unsubscribe()
+		})
+	}
+	subscribe(t, q3, subj, all)
+
+	for i := 0; i < count; i++ {
+		pub.Publish(subj, []byte(fmt.Sprint(i)))
+	}
+	if !all.wait(count) {
+		t.Fatal("subscriber without queue group should receive every message")
+	}
+	time.Sleep(settleTime)
+
+	if n := len(r1.messages()) + len(r2.messages()); n != count {
+		t.Errorf("queue group received %d messages, want %d", n, count)
+	}
+}
+
+// testOrderedDelivery checks the messages of a publisher are handled one by one in publish order by a subscription
+func testOrderedDelivery(t *testing.T, factory Factory) {
+	const count = 500
+
+	subj := uniqueSubj(t)
+	pub, sub := factory(t), factory(t)
+	r := newRecorder(t)
+	subscribe(t, sub, subj, r)
+
+	for i := 0; i < count; i++ {
+		if err := pub.Publish(subj, []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	if !r.wait(count) {
+		t.Fatalf("received %d of %d messages", len(r.messages()), count)
+	}
+
+	for i, m := range r.messages() {
+		if m != fmt.Sprint(i) {
+			t.Fatalf("message %d:%s out of order", i, m)
+		}
+	}
+	if r.concurrent.Load() {
+		t.Error("callbacks of a subscription called concurrently")
+	}
+}
+
+// testErrorDelivery checks every subscription receives Callback(nil, err) once the transport is broken,
+// and no message is delivered after the error
+func testErrorDelivery(t *testing.T, transport Transport) {
+	factory, breaker := transport(t)
+	subj := uniqueSubj(t)
+	q := factory(t)
+	r1, r2 := newRecorder(t), newRecorder(t)
+	r1.expectErrs, r2.expectErrs = true, true
+	if _, err := q.Subscribe(subj, r1); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := factory(t).Subscribe(subj, r2); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	q.Publish(subj, []byte("before"))
+	if !r1.wait(1) || !r2.wait(1) {
+		t.Fatal("message not delivered")
+	}
+
+	breaker()
+	if !r1.wait(1) || !r2.wait(1) {
+		t.Fatal("error not delivered after the transport is broken")
+	}
+	q.Publish(subj, []byte("after"))
+	time.Sleep(settleTime)
+
+	for _, r := range []*recorder{r1, r2} {
+		if errs := r.errors(); len(errs) != 1 || errs[0] == nil {
+			t.Errorf("errors:%v, want one", errs)
+		}
+		if msgs := r.messages(); len(msgs) != 1 || msgs[0] != "before" {
+			t.Errorf("messages:%v != [before]", msgs)
+		}
+	}
+}
@@ -10,11 +10,9 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
+// MQueen is a mq.MQueen on a nats connection, it can hold many subscriptions
 type MQueen struct {
-	sub         *nats.Subscription
-	conn        *nats.Conn
-	wg          sync.WaitGroup
-	isSubscribe bool
+	conn *nats.Conn
 }
 
 func NewMQueen(conn *nats.Conn) *MQueen {
@@ -23,71 +21,109 @@ func NewMQueen(conn *nats.Conn) *MQueen {
 	}
 }
 
-func (mq *MQueen) Init() error {
-	mq.isSubscribe = false
-	return nil
-}
-
 func (mq *MQueen) Publish(subj string, data []byte) error {
 	return mq.conn.Publish(subj, data)
 }
 
-func (mq *MQueen) Subscribe(subj string, cb mq.MQCallback) error {
-	if mq.isSubscribe {
-		return errors.New("already subscribed")
+func (mq *MQueen) Subscribe(subj string, cb mq.MQCallback) (mq.Subscription, error) {
+	return mq.subscribe(subj, "", cb)
+}
+
+// QueueSubscribe subscribes subj in the queue group,
+// each message is delivered to only one subscription of the group.
+func (mq *MQueen) QueueSubscribe(subj string, queue string, cb mq.MQCallback) (mq.Subscription, error) {
+	if queue == "" {
+		return nil, errors.New("empty queue group")
 	}
+	return mq.subscribe(subj, queue, cb)
+}
 
-	var err error
-	mq.sub, err = mq.conn.SubscribeSync(subj)
+func (mq *MQueen) subscribeSync(subj string, queue string) (*nats.Subscription, error) {
+	if queue == "" {
+		return mq.conn.SubscribeSync(subj)
+	}
+	return mq.conn.QueueSubscribeSync(subj, queue)
+}
+
+func (mq *MQueen) subscribe(subj string, queue string, cb mq.MQCallback) (mq.Subscription, error) {
+	sub, err := mq.subscribeSync(subj, queue)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	mq.isSubscribe = true
+	s := &subscription{
+		mq:          mq,
+		sub:         sub,
+		subj:        subj,
+		queue:       queue,
+		isSubscribe: true,
+	}
 
+	s.wg.Add(1)
 	go func() {
-		mq.on_request_handle(subj, cb)
+		defer s.wg.Done()
+		s.on_request_handle(sub, cb)
 	}()
-	return nil
+	return s, nil
 }
 
-func (mq *MQueen) UnSubscribe() error {
-	if !mq.isSubscribe {
+type subscription struct {
+	mq          *MQueen
+	sub         *nats.Subscription
+	subj        string
+	queue       string
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	isSubscribe bool
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.mu.Lock()
+	if !s.isSubscribe {
+		s.mu.Unlock()
 		return errors.New("not subscribed")
 	}
-	mq.isSubscribe = false
-	mq.sub.Unsubscribe()
-	mq.wg.Wait()
+	s.isSubscribe = false
+	sub := s.sub
+	s.sub = nil
+	s.mu.Unlock()
+
+	sub.Unsubscribe()
+	s.wg.Wait()
 
 	return nil
 }
 
-func (mq *MQueen) on_request_handle(subj string, cb mq.MQCallback) {
-	mq.wg.Add(1)
-	defer mq.wg.Done()
-
+// on_request_handle reads messages from sub until it is unsubscribed
+func (s *subscription) on_request_handle(sub *nats.Subscription, cb mq.MQCallback) {
 	for {
-		msg, err := mq.sub.NextMsg(time.Minute)
+		msg, err := sub.NextMsg(time.Minute)
 		if err != nil {
 			if err == nats.ErrTimeout {
 				continue
 			}
 
-			if !mq.sub.IsValid() && mq.isSubscribe {
+			s.mu.Lock()
+			if !s.isSubscribe {
+				// unsubscribed
+				s.mu.Unlock()
+				return
+			}
+
+			if !sub.IsValid() {
 				// re-subscribe
-				mq.sub, err = mq.conn.SubscribeSync(subj)
+				sub, err = s.mq.subscribeSync(s.subj, s.queue)
 				if err != nil {
+					s.isSubscribe = false
+					s.sub = nil
+					s.mu.Unlock()
 					cb.Callback(nil, err)
 					return
 				}
+				s.sub = sub
 			}
-
-			if mq.sub.IsValid() {
-				continue
-			}
-
-			// if sub is not valid, return
-			return
+			s.mu.Unlock()
+			continue
 		}
 
 		cb.Callback(msg.Data, nil)
@@ -0,0 +1,39 @@
+package natsmq
+
+import (
+	"os"
+	"testing"
+
+	"github.com/yc90s/xrpc/mq"
+	"github.com/yc90s/xrpc/mq/mqtest"
+
+	"github.com/nats-io/nats.go"
+)
+
+// TestConformance needs a running nats server, set XRPC_NATS_URL to run it
+// e.g. XRPC_NATS_URL=nats://127.0.0.1:4222 go test ./mq/nats
+func TestConformance(t *testing.T) {
+	url := os.Getenv("XRPC_NATS_URL")
+	if url == "" {
+		t.Skip("XRPC_NATS_URL not set")
+	}
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	mqtest.RunConformance(t, func(t *testing.T) mq.MQueen {
+		return NewMQueen(nc)
+	}, mqtest.WithTransport(func(t *testing.T) (mqtest.Factory, func()) {
+		conn, err := nats.Connect(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(conn.Close)
+		return func(t *testing.T) mq.MQueen {
+			return NewMQueen(conn)
+		}, conn.Close
+	}))
+}
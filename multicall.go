@@ -0,0 +1,187 @@
+package xrpc
+
+import (
+	"context"
+	"fmt"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrNoQuorum error = NewError(Unavailable, "quorum not reached")
+)
+
+// MulticallPolicy decides when Multicall stops waiting for the replies,
+// it waits until the deadline if neither is set.
+type MulticallPolicy struct {
+	Expected int // stop after the replies of Expected servers, it fails with ErrTimeout if not reached
+	Quorum   int // stop after Quorum servers succeeded, it fails with ErrNoQuorum if not reached
+}
+
+// Reply is the reply of one server to Multicall
+type Reply struct {
+	Responder string   // the id of the server, see SetServerID
+	Err       error    // the error returned by the method
+	Trailer   Metadata // the trailer set by the server
+
+	c        *RPCClient
+	response *xrpcpb.Response
+}
+
+// Decode unmarshals the results of the method into reply, which may be Replies
+func (r *Reply) Decode(reply any) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return r.c.decodeReply(r.response, reply)
+}
+
+// multicallBuffer is the number of replies buffered by Multicall
+const multicallBuffer = 128
+
+// multicall receives the replies of a Multicall
+type multicall struct {
+	replies chan *xrpcpb.Response
+	done    chan struct{}
+}
+
+// deliver is called by RPCClient.Callback, the replies after done are dropped
+func (m *multicall) deliver(response *xrpcpb.Response) {
+	select {
+	case m.replies <- response:
+	case <-m.done:
+	}
+}
+
+// Multicall calls the method of every server subscribing subj without a queue group,
+// and collects the replies until the timeout of options, e.g.
+//
+//	replies, err := client.Multicall(subj, "Stats")
+//	for _, r := range replies {
+//		var stats Stats
+//		err := r.Decode(&stats)
+//		...
+//	}
+//
+// goroutine safe
+func (c *RPCClient) Multicall(subj string, methodName string, args ...any) ([]*Reply, error) {
+	return c.MulticallContext(context.Background(), subj, methodName, MulticallPolicy{}, args...)
+}
+
+// MulticallContext is like Multicall, it stops when the policy is satisfied or ctx is done.
+// If ctx has no deadline, the timeout of options is used.
+// The replies received are returned even if it fails, one per server in order of arrival.
+// The client interceptors and retry policy are not applied.
+// goroutine safe
+func (c *RPCClient) MulticallContext(ctx context.Context, subj string, methodName string, policy MulticallPolicy, args ...any) ([]*Reply, error) {
+	if !c.isValid {
+		err := c.retry()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel, _ := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	remaining, err := remainingTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	randCid, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	argsData, err := c.marshalArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	md, _ := FromOutgoingContext(ctx)
+	request := &xrpcpb.Request{
+		Cid:      randCid.String(),
+		ReplyTo:  c.opts.subj,
+		Method:   methodName,
+		Params:   argsData,
+		Timeout:  remaining,
+		Metadata: md,
+	}
+
+	requestData, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	call := &multicall{
+		replies: make(chan *xrpcpb.Response, multicallBuffer),
+		done:    make(chan struct{}),
+	}
+	c.calls.Store(request.Cid, call)
+	defer func() {
+		c.calls.Delete(request.Cid)
+		close(call.done)
+	}()
+
+	err = c.opts.mq.Publish(subj, requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var replies []*Reply
+	responders := make(map[string]bool)
+	succeeded := 0
+	for {
+		if policy.Quorum > 0 && succeeded >= policy.Quorum {
+			return replies, nil
+		}
+		if policy.Expected > 0 && len(replies) >= policy.Expected {
+			if policy.Quorum > 0 {
+				return replies, fmt.Errorf("%w: %d of %d", ErrNoQuorum, succeeded, policy.Quorum)
+			}
+			return replies, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			switch {
+			case ctx.Err() != context.DeadlineExceeded:
+				return replies, ctx.Err()
+			case policy.Quorum > 0:
+				return replies, fmt.Errorf("%w: %d of %d", ErrNoQuorum, succeeded, policy.Quorum)
+			case policy.Expected > 0:
+				return replies, ErrTimeout
+			}
+			// no policy, the replies until the deadline are all
+			return replies, nil
+		case response := <-call.replies:
+			// a server may reply again, e.g. the duplicate request
+			if response.Responder != "" {
+				if responders[response.Responder] {
+					continue
+				}
+				responders[response.Responder] = true
+			}
+
+			reply := &Reply{
+				Responder: response.Responder,
+				Err:       c.responseError(response),
+				Trailer:   response.Metadata,
+				c:         c,
+				response:  response,
+			}
+			if reply.Err == nil {
+				succeeded++
+			}
+			replies = append(replies, reply)
+		}
+	}
+}
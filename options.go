@@ -18,6 +18,30 @@ type Options struct {
 	mq      mq.MQueen
 	subj    string
 	timeout time.Duration
+
+	queueGroup string
+	serverID   string
+	dedupTTL   time.Duration
+	dedupSize  int
+
+	maxConcurrency    int
+	methodConcurrency map[string]int
+	poolWorkers       int
+	poolQueueSize     int
+	overloadPolicy    OverloadPolicy
+
+	streamWindow int
+
+	retryPolicy *RetryPolicy
+
+	batchLinger  time.Duration
+	batchMaxSize int
+
+	serverInterceptors []UnaryServerInterceptor
+	serverInterceptor  UnaryServerInterceptor // chained serverInterceptors
+
+	clientInterceptors []UnaryClientInterceptor
+	clientInterceptor  UnaryClientInterceptor // chained clientInterceptors
 }
 
 type Option func(*Options)
@@ -45,3 +69,112 @@ func SetTimeout(timeout time.Duration) Option {
 		o.timeout = timeout
 	}
 }
+
+// SetServerInterceptor adds interceptors around the method dispatch of RPCServer,
+// the first one is the outermost.
+func SetServerInterceptor(interceptors ...UnaryServerInterceptor) Option {
+	return func(o *Options) {
+		o.serverInterceptors = append(o.serverInterceptors, interceptors...)
+	}
+}
+
+// SetClientInterceptor adds interceptors around every Call and Cast of RPCClient,
+// the first one is the outermost.
+func SetClientInterceptor(interceptors ...UnaryClientInterceptor) Option {
+	return func(o *Options) {
+		o.clientInterceptors = append(o.clientInterceptors, interceptors...)
+	}
+}
+
+// SetQueueGroup makes RPCServer subscribe its subject in the queue group,
+// so that each request is handled by only one of the servers in the group.
+// The message queue must implement mq.QueueSubscriber.
+// Without a queue group every server subscribing the subject handles every request.
+func SetQueueGroup(group string) Option {
+	return func(o *Options) {
+		o.queueGroup = group
+	}
+}
+
+// SetServerID sets the id RPCServer replies with, which tells the replies of Multicall apart.
+// default a random uuid
+func SetServerID(id string) Option {
+	return func(o *Options) {
+		o.serverID = id
+	}
+}
+
+// SetRetryPolicy makes RPCClient retry the failed Call of the methods opted in by the policy
+func SetRetryPolicy(policy RetryPolicy) Option {
+	return func(o *Options) {
+		o.retryPolicy = &policy
+	}
+}
+
+// SetDedup makes RPCServer remember the handled requests by Cid for ttl, at most size of them.
+// A duplicate request gets the remembered response instead of running the method again,
+// which makes it safe to retry non-idempotent methods.
+func SetDedup(ttl time.Duration, size int) Option {
+	return func(o *Options) {
+		o.dedupTTL = ttl
+		o.dedupSize = size
+	}
+}
+
+// SetMaxConcurrency limits the number of requests of the RegisterGO methods being executed
+// or queued in the worker pool at the same time. default 0, unlimited
+func SetMaxConcurrency(n int) Option {
+	return func(o *Options) {
+		o.maxConcurrency = n
+	}
+}
+
+// SetMethodConcurrency limits the number of requests of the RegisterGO method name
+// being executed or queued in the worker pool at the same time. default 0, unlimited
+func SetMethodConcurrency(name string, n int) Option {
+	return func(o *Options) {
+		if o.methodConcurrency == nil {
+			o.methodConcurrency = make(map[string]int)
+		}
+		o.methodConcurrency[name] = n
+	}
+}
+
+// SetWorkerPool makes RPCServer execute the RegisterGO methods by a pool of workers goroutines
+// instead of a new goroutine per request, at most queueSize requests wait for a free worker.
+func SetWorkerPool(workers int, queueSize int) Option {
+	return func(o *Options) {
+		o.poolWorkers = workers
+		o.poolQueueSize = queueSize
+	}
+}
+
+// SetOverloadPolicy sets what to do when the concurrency limit is reached
+// or the worker pool queue is full. default OverloadBlock
+func SetOverloadPolicy(policy OverloadPolicy) Option {
+	return func(o *Options) {
+		o.overloadPolicy = policy
+	}
+}
+
+// SetStreamWindow sets the number of messages the peer of a stream can send before they are consumed.
+// For the server it limits the clients sending to its methods, for the client it limits the servers
+// sending to its streams. default 64
+func SetStreamWindow(n int) Option {
+	return func(o *Options) {
+		o.streamWindow = n
+	}
+}
+
+// SetMicroBatch makes RPCClient coalesce the Call and Cast requests to the same subject,
+// they are sent in one message after linger or when maxSize of them are queued.
+// A call with a deadline sooner than linger is sent earlier, and the timeout sent to the server
+// is counted from the time it's sent. If the publish fails, the queued Calls fail with Unavailable,
+// while the queued Casts have returned nil: the batched Casts never return the errors of the mq, they are only logged.
+// Disabled if linger <= 0 or maxSize <= 1.
+func SetMicroBatch(linger time.Duration, maxSize int) Option {
+	return func(o *Options) {
+		o.batchLinger = linger
+		o.batchMaxSize = maxSize
+	}
+}
@@ -0,0 +1,88 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+)
+
+// ReservedPrefix is the prefix of the built-in method names, it can't be used by Register
+const ReservedPrefix = "xrpc."
+
+// ReflectMethod is the built-in method listing the methods registered to RPCServer,
+// it takes no arg and replies *xrpcpb.ReflectReply.
+const ReflectMethod = ReservedPrefix + "reflect"
+
+var ErrReservedMethod = errors.New("method name reserved")
+
+func isReservedMethod(name string) bool {
+	return strings.HasPrefix(name, ReservedPrefix)
+}
+
+// reflect implements ReflectMethod
+func (s *RPCServer) reflect() (*xrpcpb.ReflectReply, error) {
+	s.methodsMu.RLock()
+	defer s.methodsMu.RUnlock()
+
+	reply := &xrpcpb.ReflectReply{
+		Methods: make([]*xrpcpb.MethodDesc, 0, len(s.methods)),
+	}
+	for name, methodInfo := range s.methods {
+		reply.Methods = append(reply.Methods, methodInfo.desc(name))
+	}
+	sort.Slice(reply.Methods, func(i, j int) bool {
+		return reply.Methods[i].Name < reply.Methods[j].Name
+	})
+	return reply, nil
+}
+
+func (m *MethodInfo) desc(name string) *xrpcpb.MethodDesc {
+	desc := &xrpcpb.MethodDesc{
+		Name:       name,
+		Goroutine:  m.Goroutine,
+		HasContext: m.HasContext,
+	}
+
+	inType := m.InType
+	if m.HasContext {
+		inType = inType[1:]
+	}
+	if m.Stream {
+		// the message types are the arg of Send and the reply of Recv of the stream
+		stream := inType[len(inType)-1]
+		if send, ok := stream.MethodByName("Send"); ok {
+			desc.Stream = send.Type.In(1).String()
+		}
+		if recv, ok := stream.MethodByName("Recv"); ok {
+			desc.RecvStream = recv.Type.Out(0).String()
+		}
+		inType = inType[:len(inType)-1]
+	}
+	for i, t := range inType {
+		if m.MethodType.IsVariadic() && i == len(inType)-1 {
+			desc.Args = append(desc.Args, "..."+t.Elem().String())
+		} else {
+			desc.Args = append(desc.Args, t.String())
+		}
+	}
+
+	// the trailing error is not a reply
+	if len(m.OutType) > 0 {
+		for _, t := range m.OutType[:len(m.OutType)-1] {
+			desc.Returns = append(desc.Returns, t.String())
+		}
+	}
+	return desc
+}
+
+// Reflect lists the methods registered to the server subscribing subj by calling ReflectMethod
+func (c *RPCClient) Reflect(ctx context.Context, subj string) ([]*xrpcpb.MethodDesc, error) {
+	reply := new(xrpcpb.ReflectReply)
+	if err := c.CallContext(ctx, subj, ReflectMethod, reply); err != nil {
+		return nil, err
+	}
+	return reply.Methods, nil
+}
@@ -0,0 +1,100 @@
+package xrpc
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy defines how RPCClient retries a failed Call.
+// All attempts of a Call are sent with the same Cid, so that servers can deduplicate them.
+// Only idempotent methods or methods served with deduplication should be opted in.
+type RetryPolicy struct {
+	MaxAttempts       int           // max number of attempts including the first one, <= 1 means no retry
+	InitialBackoff    time.Duration // backoff before the first retry
+	MaxBackoff        time.Duration // max backoff, 0 means no limit
+	Multiplier        float64       // backoff is multiplied by it after each retry, default 2
+	Jitter            float64       // backoff is randomized in [1-Jitter, 1+Jitter] times, 0~1
+	PerAttemptTimeout time.Duration // timeout of each attempt, default the timeout of options
+	RetryableCodes    []Code        // codes of errors to retry, default DeadlineExceeded and Unavailable
+	Methods           []string      // methods opted in, "*" means all methods
+}
+
+// enabled reports whether the method is opted in
+func (p *RetryPolicy) enabled(methodName string) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	for _, m := range p.Methods {
+		if m == "*" || m == methodName {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	code := CodeOf(err)
+	if len(p.RetryableCodes) == 0 {
+		return code == DeadlineExceeded || code == Unavailable
+	}
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the time to wait before the retry-th retry, retry starts from 1
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(rand.Float64()*2-1)
+	}
+	return time.Duration(d)
+}
+
+// callWithRetry calls the method until it succeeds, fails with a non-retryable error,
+// runs out of attempts or ctx is done.
+func (c *RPCClient) callWithRetry(ctx context.Context, cid string, subj string, methodName string, reply any, args ...any) error {
+	policy := c.opts.retryPolicy
+	timeout := policy.PerAttemptTimeout
+	if timeout <= 0 {
+		timeout = c.opts.timeout
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = c._call(attemptCtx, cid, subj, methodName, reply, args...)
+		cancel()
+
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if err == context.DeadlineExceeded && ctx.Err() == nil {
+		// the attempt timed out but the caller didn't
+		return ErrTimeout
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package xrpc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/golang/glog"
+)
+
+var ErrNoSuitableMethod = errors.New("service has no suitable method")
+
+// RegisterService registers the exported methods of rcvr as "name.Method", in the style of net/rpc.
+// The name defaults to the type name of rcvr. The methods not suitable are skipped silently,
+// it returns ErrNoSuitableMethod if there is no suitable method,
+// or the errors of the suitable methods failed to register, joined.
+func (s *RPCServer) RegisterService(name string, rcvr any) error {
+	return s._registerService(name, rcvr, false)
+}
+
+// RegisterServiceGO is like RegisterService, but the methods run in a new goroutine like RegisterGO
+func (s *RPCServer) RegisterServiceGO(name string, rcvr any) error {
+	return s._registerService(name, rcvr, true)
+}
+
+func (s *RPCServer) _registerService(name string, rcvr any, goroutine bool) error {
+	typ := reflect.TypeOf(rcvr)
+	val := reflect.ValueOf(rcvr)
+	if name == "" {
+		name = reflect.Indirect(val).Type().Name()
+	}
+	if name == "" {
+		return fmt.Errorf("no service name for type %s", typ)
+	}
+	// the method names must not be reserved
+	if isReservedMethod(name + ".") {
+		return ErrReservedMethod
+	}
+
+	var errs []error
+	suitable, registered := 0, 0
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if !method.IsExported() {
+			continue
+		}
+
+		methodName := name + "." + method.Name
+		err := s._register(methodName, val.Method(i).Interface(), goroutine)
+		if errors.Is(err, ErrMethodNotSuitable) {
+			// e.g. String() string
+			glog.V(1).Info("skip method ", methodName, ": ", err)
+			continue
+		}
+		suitable++
+		if err != nil {
+			glog.Info("register ", methodName, " error: ", err)
+			errs = append(errs, fmt.Errorf("%s: %w", methodName, err))
+			continue
+		}
+		registered++
+	}
+
+	if suitable == 0 {
+		errs = append(errs, fmt.Errorf("%s: %w", name, ErrNoSuitableMethod))
+	} else if registered > 0 {
+		s.SetServingStatus(name, Serving)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,256 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/yc90s/xrpc/codec"
+	xrpcpb "github.com/yc90s/xrpc/pb"
+
+	"github.com/golang/glog"
+)
+
+// Code is the error code of a rpc error
+type Code int32
+
+const (
+	OK Code = iota
+	Canceled
+	Unknown
+	InvalidArgument
+	DeadlineExceeded
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	ResourceExhausted
+	FailedPrecondition
+	Aborted
+	OutOfRange
+	Unimplemented
+	Internal
+	Unavailable
+	DataLoss
+	Unauthenticated
+)
+
+var codeNames = [...]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if c >= 0 && int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return fmt.Sprintf("Code(%d)", int32(c))
+}
+
+// Error is a rpc error with a code, it is sent to the caller as it is.
+// Details can be any registered types, see RegisterDetail.
+type Error struct {
+	Code    Code
+	Message string
+	Details []any
+}
+
+// NewError creates an Error
+func NewError(code Code, msg string, details ...any) *Error {
+	return &Error{
+		Code:    code,
+		Message: msg,
+		Details: details,
+	}
+}
+
+// Errorf creates an Error with a formatted message
+func Errorf(code Code, format string, a ...any) *Error {
+	return NewError(code, fmt.Sprintf(format, a...))
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is an Error with the same code,
+// the message is compared only if the message of target is not empty.
+// DeadlineExceeded and Canceled also match the errors of context.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case context.DeadlineExceeded:
+		return e.Code == DeadlineExceeded
+	case context.Canceled:
+		return e.Code == Canceled
+	}
+
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code && (t.Message == "" || t.Message == e.Message)
+}
+
+// Unwrap returns the details which are errors, so that errors.As can find custom error types
+func (e *Error) Unwrap() []error {
+	var errs []error
+	for _, d := range e.Details {
+		if err, ok := d.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// CodeOf returns the code of err, Unknown if err is not an Error
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	}
+	return Unknown
+}
+
+// detail types registry
+var (
+	detailMu    sync.RWMutex
+	detailTypes = make(map[string]reflect.Type)
+	detailNames = make(map[reflect.Type]string)
+)
+
+// RegisterDetail registers the type of v by name, so that the values of it can be sent as error details.
+// If the type is an error, it is also found in the error chain returned by methods,
+// and can be retrieved by errors.As on the client.
+// The type must be supported by the codec, and both server and client must register it with the same name.
+func RegisterDetail(name string, v any) {
+	rt := reflect.TypeOf(v)
+
+	detailMu.Lock()
+	defer detailMu.Unlock()
+	detailTypes[name] = rt
+	detailNames[rt] = name
+}
+
+func detailName(rt reflect.Type) (string, bool) {
+	detailMu.RLock()
+	defer detailMu.RUnlock()
+	name, ok := detailNames[rt]
+	return name, ok
+}
+
+func detailType(name string) (reflect.Type, bool) {
+	detailMu.RLock()
+	defer detailMu.RUnlock()
+	rt, ok := detailTypes[name]
+	return rt, ok
+}
+
+// registeredErrors walks the error chain of err and returns the errors of registered types
+func registeredErrors(err error) []any {
+	var details []any
+	var walk func(error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		if _, ok := detailName(reflect.TypeOf(err)); ok {
+			details = append(details, err)
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(x.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, e := range x.Unwrap() {
+				walk(e)
+			}
+		}
+	}
+	walk(err)
+	return details
+}
+
+// toStatus converts err to the wire status
+func toStatus(err error, c codec.Codec) *xrpcpb.Status {
+	st := &xrpcpb.Status{
+		Code:    int32(CodeOf(err)),
+		Message: err.Error(),
+	}
+
+	var details []any
+	var e *Error
+	if errors.As(err, &e) {
+		details = e.Details
+	} else {
+		details = registeredErrors(err)
+	}
+
+	for _, d := range details {
+		name, ok := detailName(reflect.TypeOf(d))
+		if !ok {
+			glog.Info("detail type not registered: ", reflect.TypeOf(d))
+			continue
+		}
+		b, err := c.Marshal(d)
+		if err != nil {
+			glog.Info("codec.Marshal detail error: ", err)
+			continue
+		}
+		st.Details = append(st.Details, &xrpcpb.Detail{Type: name, Value: b})
+	}
+	return st
+}
+
+// fromStatus converts the wire status to an Error
+func fromStatus(st *xrpcpb.Status, c codec.Codec) *Error {
+	e := NewError(Code(st.Code), st.Message)
+	for _, d := range st.Details {
+		rt, ok := detailType(d.Type)
+		if !ok {
+			glog.Info("detail type not registered: ", d.Type)
+			continue
+		}
+
+		var v reflect.Value
+		if rt.Kind() == reflect.Ptr {
+			v = reflect.New(rt.Elem())
+		} else {
+			v = reflect.New(rt)
+		}
+		if err := c.Unmarshal(d.Value, v.Interface()); err != nil {
+			glog.Info("codec.Unmarshal detail error: ", err)
+			continue
+		}
+		if rt.Kind() != reflect.Ptr {
+			v = v.Elem()
+		}
+		e.Details = append(e.Details, v.Interface())
+	}
+	return e
+}
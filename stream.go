@@ -0,0 +1,879 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	xrpcpb "github.com/yc90s/xrpc/pb"
+
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrNoReplyTo    = errors.New("no reply subject")
+	ErrStreamClosed = errors.New("stream closed")
+)
+
+// defaultStreamWindow is the default number of messages the peer of a stream can send before they are consumed
+const defaultStreamWindow = 64
+
+// ServerStream sends and receives the messages of a streaming call
+type ServerStream struct {
+	ctx     context.Context
+	cancel  context.CancelFunc // called when the client cancels the stream
+	server  *RPCServer
+	request *xrpcpb.Request
+	mu      sync.Mutex
+	seq     int64 // seq of the last message sent
+
+	// the messages the client allows to send, unlimited if the request has no window
+	credits int64
+	granted chan struct{} // notified when credits are granted
+
+	// the messages from the client, nil if the method doesn't receive
+	recv     chan *xrpcpb.Request
+	pending  map[int64]*xrpcpb.Request // the messages arrived out of order
+	next     int64                     // seq of the next message to receive
+	consumed int64                     // the messages received since the last grant
+	recvErr  error                     // io.EOF after the client finished sending
+	failErr  error                     // the error canceling the stream, e.g. the window exceeded
+}
+
+// SendStream is the typed sender of a server-streaming method, it's the last arg of the method, e.g.
+//
+//	func (s *Service) List(prefix string, stream *xrpc.SendStream[*pb.Item]) error
+//
+// The error returned by the method is sent to the caller as the final status.
+type SendStream[T any] struct {
+	*ServerStream
+}
+
+// Send sends m to the caller, goroutine safe
+func (s *SendStream[T]) Send(m T) error {
+	return s.SendMsg(m)
+}
+
+// RecvStream is the typed receiver of a client-streaming method, it's the last arg of the method, e.g.
+//
+//	func (s *Service) Upload(name string, stream *xrpc.RecvStream[[]byte]) (int, error)
+//
+// The reply of the method is sent to the caller after it returns.
+type RecvStream[T any] struct {
+	*ServerStream
+}
+
+// Recv receives the next message, see RecvMsg
+func (s *RecvStream[T]) Recv() (T, error) {
+	return recvTyped[T](s.RecvMsg)
+}
+
+// BidiStream is the typed stream of a bidirectional streaming method, it's the last arg of the method, e.g.
+//
+//	func (s *Service) Chat(stream *xrpc.BidiStream[*pb.Msg, *pb.Msg]) error
+type BidiStream[Req any, Resp any] struct {
+	*ServerStream
+}
+
+// Recv receives the next message, see RecvMsg
+func (s *BidiStream[Req, Resp]) Recv() (Req, error) {
+	return recvTyped[Req](s.RecvMsg)
+}
+
+// Send sends m to the caller, goroutine safe
+func (s *BidiStream[Req, Resp]) Send(m Resp) error {
+	return s.SendMsg(m)
+}
+
+type serverStreamKey struct{}
+
+func newServerStream(ctx context.Context, server *RPCServer, request *xrpcpb.Request, recv bool) (context.Context, *ServerStream) {
+	ss := &ServerStream{
+		server:  server,
+		request: request,
+		credits: request.Credits,
+		granted: make(chan struct{}, 1),
+	}
+	if recv {
+		// the client never sends more than the window, and the end of stream
+		ss.recv = make(chan *xrpcpb.Request, server.streamWindow()+1)
+		ss.pending = make(map[int64]*xrpcpb.Request)
+		ss.next = 1
+	}
+	ctx, ss.cancel = context.WithCancel(context.WithValue(ctx, serverStreamKey{}, ss))
+	ss.ctx = ctx
+	return ctx, ss
+}
+
+// Context returns the context of the call
+func (ss *ServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+// SendMsg sends m to the caller, goroutine safe.
+// It blocks until the caller grants the credit, so that a slow receiver isn't flooded,
+// and returns the error of the context after the caller cancelled the stream.
+func (ss *ServerStream) SendMsg(m any) error {
+	if ss.ctx.Err() != nil {
+		return ss.err()
+	}
+	if ss.request.ReplyTo == "" {
+		return ErrNoReplyTo
+	}
+
+	b, err := ss.server.opts.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := ss.waitCredit(); err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.seq++
+	data, err := proto.Marshal(&xrpcpb.Response{
+		Cid:    ss.request.Cid,
+		Result: b,
+		Seq:    ss.seq,
+	})
+	if err != nil {
+		return err
+	}
+	return ss.server.opts.mq.Publish(ss.request.ReplyTo, data)
+}
+
+// RecvMsg receives the next message from the caller into m, it returns io.EOF after the caller finished sending.
+// It's not goroutine safe.
+func (ss *ServerStream) RecvMsg(m any) error {
+	if ss.recv == nil {
+		return errors.New("the method doesn't receive stream")
+	}
+	if ss.recvErr != nil {
+		return ss.recvErr
+	}
+
+	var frame *xrpcpb.Request
+	for frame == nil {
+		if f, ok := ss.pending[ss.next]; ok {
+			delete(ss.pending, ss.next)
+			ss.next++
+			frame = f
+			break
+		}
+
+		select {
+		case <-ss.ctx.Done():
+			return ss.err()
+		case f := <-ss.recv:
+			ss.pending[f.Seq] = f
+		}
+	}
+
+	if frame.EndOfStream {
+		ss.recvErr = io.EOF
+		return io.EOF
+	}
+
+	// grant the credits consumed when half of the window is used
+	ss.consumed++
+	if ss.consumed >= (ss.server.streamWindow()+1)/2 {
+		if err := ss.grant(ss.consumed); err != nil {
+			glog.Error("grant error: ", err)
+		}
+		ss.consumed = 0
+	}
+
+	if len(frame.Params) == 0 {
+		return errors.New("empty stream message")
+	}
+	return ss.server.opts.codec.Unmarshal(frame.Params[0], m)
+}
+
+// waitCredit waits for a credit to send if the caller limits the window
+func (ss *ServerStream) waitCredit() error {
+	if ss.request.Credits <= 0 {
+		return nil
+	}
+	for {
+		ss.mu.Lock()
+		if ss.credits > 0 {
+			ss.credits--
+			ss.mu.Unlock()
+			return nil
+		}
+		ss.mu.Unlock()
+
+		select {
+		case <-ss.granted:
+		case <-ss.ctx.Done():
+			return ss.err()
+		}
+	}
+}
+
+// grant allows the client to send n more messages to the inbox of the server,
+// n is 0 if the method doesn't receive, the client only learns the inbox to cancel the stream
+func (ss *ServerStream) grant(n int64) error {
+	data, err := proto.Marshal(&xrpcpb.Response{
+		Cid:      ss.request.Cid,
+		Credits:  n,
+		StreamTo: ss.server.inboxSubj(),
+	})
+	if err != nil {
+		return err
+	}
+	return ss.server.opts.mq.Publish(ss.request.ReplyTo, data)
+}
+
+// deliver is called by the inbox of the server, it never blocks
+func (ss *ServerStream) deliver(frame *xrpcpb.Request) {
+	switch {
+	case frame.Cancel:
+		ss.cancel()
+		return
+	case frame.Credits > 0:
+		ss.mu.Lock()
+		ss.credits += frame.Credits
+		ss.mu.Unlock()
+
+		select {
+		case ss.granted <- struct{}{}:
+		default:
+		}
+		return
+	case ss.recv == nil:
+		glog.Error("the method doesn't receive stream: ", frame.Cid)
+		return
+	}
+
+	select {
+	case ss.recv <- frame:
+	default:
+		glog.Error("stream window exceeded: ", frame.Cid)
+		ss.fail(Errorf(ResourceExhausted, "stream window exceeded"))
+	}
+}
+
+// fail cancels the stream, SendMsg and RecvMsg return err
+func (ss *ServerStream) fail(err error) {
+	ss.mu.Lock()
+	if ss.failErr == nil {
+		ss.failErr = err
+	}
+	ss.mu.Unlock()
+	ss.cancel()
+}
+
+// err returns the error of the stream after its context is done
+func (ss *ServerStream) err() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.failErr != nil {
+		return ss.failErr
+	}
+	return ss.ctx.Err()
+}
+
+// end returns the seq of the end of stream
+func (ss *ServerStream) end() int64 {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.seq++
+	return ss.seq
+}
+
+// newStreamArg makes the typed stream t of the method from the ServerStream in ctx
+func newStreamArg(ctx context.Context, t reflect.Type) reflect.Value {
+	ss, _ := ctx.Value(serverStreamKey{}).(*ServerStream)
+	v := reflect.New(t.Elem())
+	v.Elem().Field(0).Set(reflect.ValueOf(ss))
+	return v
+}
+
+// streamWindow returns the window of the streams receiving from the clients
+func (s *RPCServer) streamWindow() int64 {
+	return streamWindow(&s.opts)
+}
+
+func streamWindow(opts *Options) int64 {
+	if opts.streamWindow <= 0 {
+		return defaultStreamWindow
+	}
+	return int64(opts.streamWindow)
+}
+
+// inboxSubj returns the subject the clients of the streams send messages to
+func (s *RPCServer) inboxSubj() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inboxSubject
+}
+
+// openStream routes the messages of the stream to it, and grants the initial window to the client
+// if the method receives. The inbox of the server is subscribed on the first stream.
+func (s *RPCServer) openStream(ss *ServerStream) error {
+	s.mu.Lock()
+	if s.inbox == nil {
+		randID, err := uuid.NewRandom()
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		subj := s.opts.subj + ".stream." + randID.String()
+		sub, err := s.opts.mq.Subscribe(subj, &streamInbox{server: s})
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.inbox = sub
+		s.inboxSubject = subj
+	}
+	s.mu.Unlock()
+
+	s.streams.Store(ss.request.Cid, ss)
+	if ss.recv == nil {
+		return ss.grant(0)
+	}
+	return ss.grant(s.streamWindow())
+}
+
+// closeInbox unsubscribes the inbox of the server
+func (s *RPCServer) closeInbox() {
+	s.mu.Lock()
+	inbox := s.inbox
+	s.inbox = nil
+	s.inboxSubject = ""
+	s.mu.Unlock()
+	if inbox != nil {
+		inbox.Unsubscribe()
+	}
+}
+
+// streamInbox receives the messages sent by the clients of the streams to the server
+type streamInbox struct {
+	server *RPCServer
+}
+
+func (inbox *streamInbox) Callback(data []byte, mqerr error) {
+	if mqerr != nil {
+		glog.Error(mqerr)
+		return
+	}
+
+	var frame xrpcpb.Request
+	if err := proto.Unmarshal(data, &frame); err != nil {
+		glog.Info("proto.Unmarshal error: ", err)
+		return
+	}
+
+	ss, ok := inbox.server.streams.Load(frame.Cid)
+	if !ok {
+		glog.Info("stream not found: ", frame.Cid)
+		return
+	}
+	ss.(*ServerStream).deliver(&frame)
+}
+
+// ClientStream sends and receives the messages of a streaming call.
+// SendMsg and RecvMsg can be called in different goroutines, but neither is goroutine safe.
+type ClientStream struct {
+	c         *RPCClient
+	ctx       context.Context
+	cid       string
+	msgs      chan *xrpcpb.Response
+	closed    chan struct{}
+	closeOnce sync.Once
+	next      int64                      // seq of the next message
+	pending   map[int64]*xrpcpb.Response // the messages arrived out of order
+	err       error                      // io.EOF or the final error
+	final     *xrpcpb.Response           // the end of stream
+	trailer   Metadata
+	window    int64 // the number of messages the server can send before they are received
+	consumed  int64 // the messages received since the last grant
+
+	sendMu    sync.Mutex
+	credits   int64         // the number of messages allowed to send
+	streamTo  string        // the inbox of the server, empty before the stream is opened
+	granted   chan struct{} // notified when credits are granted
+	sendSeq   int64         // seq of the last message sent
+	sendDone  bool
+	opened    bool        // the server replied
+	ended     bool        // the final status arrived
+	failErr   error       // the error failing the stream, e.g. the stream is not opened in time
+	openTimer *time.Timer // fails the stream if the server doesn't open it in time
+}
+
+// NewStream calls the streaming method, the messages are sent by SendMsg and received by RecvMsg.
+// The stream is closed when ctx is done, there is no default timeout for the whole stream,
+// but it fails with ErrTimeout if the server doesn't open it in the timeout of options.
+// The server sends at most the window of SetStreamWindow messages more than received,
+// so an unread stream never blocks the other calls of the client.
+// goroutine safe
+func (c *RPCClient) NewStream(ctx context.Context, subj string, methodName string, args ...any) (*ClientStream, error) {
+	if !c.isValid {
+		err := c.retry()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	remaining, err := remainingTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	randCid, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	argsData, err := c.marshalArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	window := streamWindow(&c.opts)
+	md, _ := FromOutgoingContext(ctx)
+	request := &xrpcpb.Request{
+		Cid:      randCid.String(),
+		ReplyTo:  c.opts.subj,
+		Method:   methodName,
+		Params:   argsData,
+		Timeout:  remaining,
+		Metadata: md,
+		Stream:   true,
+		Credits:  window,
+	}
+
+	requestData, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &ClientStream{
+		c:      c,
+		ctx:    ctx,
+		cid:    request.Cid,
+		window: window,
+		// the server never sends more than the window, and the end of stream
+		msgs:    make(chan *xrpcpb.Response, window+1),
+		closed:  make(chan struct{}),
+		next:    1,
+		pending: make(map[int64]*xrpcpb.Response),
+		granted: make(chan struct{}, 1),
+	}
+	c.calls.Store(cs.cid, cs)
+
+	if c.opts.timeout > 0 {
+		cs.sendMu.Lock()
+		cs.openTimer = time.AfterFunc(c.opts.timeout, cs.openTimeout)
+		cs.sendMu.Unlock()
+	}
+
+	err = c.opts.mq.Publish(subj, requestData)
+	if err != nil {
+		cs.fail(err)
+		c.calls.Delete(cs.cid)
+		return nil, err
+	}
+	return cs, nil
+}
+
+// openTimeout fails the stream not opened by the server
+func (cs *ClientStream) openTimeout() {
+	cs.sendMu.Lock()
+	opened := cs.opened
+	cs.sendMu.Unlock()
+	if opened {
+		return
+	}
+
+	cs.fail(ErrTimeout)
+	// the stream closed before opened waits no more
+	cs.c.calls.Delete(cs.cid)
+}
+
+// deliver is called by RPCClient.Callback, it never blocks
+func (cs *ClientStream) deliver(response *xrpcpb.Response) {
+	// the failure before streaming has no seq
+	final := response.EndOfStream || (response.Seq == 0 && response.StreamTo == "")
+
+	cs.sendMu.Lock()
+	if !cs.opened {
+		cs.opened = true
+		if cs.openTimer != nil {
+			cs.openTimer.Stop()
+		}
+	}
+	if response.StreamTo != "" {
+		// credits are handled here, so that SendMsg works without RecvMsg
+		cs.credits += response.Credits
+		cs.streamTo = response.StreamTo
+	}
+	if final {
+		// the method returned, stop sending
+		cs.sendDone = true
+		cs.ended = true
+	}
+	cs.sendMu.Unlock()
+
+	select {
+	case <-cs.closed:
+		// closed before the stream was opened, cancel it now
+		if response.StreamTo != "" && !final {
+			cs.cancel(response.StreamTo)
+		}
+		cs.c.calls.Delete(cs.cid)
+		return
+	default:
+	}
+
+	if response.StreamTo != "" || final {
+		select {
+		case cs.granted <- struct{}{}:
+		default:
+		}
+	}
+	if response.StreamTo != "" {
+		return
+	}
+
+	select {
+	case cs.msgs <- response:
+	default:
+		glog.Error("stream window exceeded: ", cs.cid)
+		cs.fail(Errorf(ResourceExhausted, "stream window exceeded"))
+	}
+}
+
+// fail closes the stream, RecvMsg returns err
+func (cs *ClientStream) fail(err error) {
+	cs.sendMu.Lock()
+	if cs.failErr == nil {
+		cs.failErr = err
+	}
+	cs.sendMu.Unlock()
+	cs.Close()
+}
+
+// closedError returns the error of RecvMsg after the stream is closed
+func (cs *ClientStream) closedError() error {
+	cs.sendMu.Lock()
+	defer cs.sendMu.Unlock()
+	if cs.failErr != nil {
+		return cs.failErr
+	}
+	return ErrStreamClosed
+}
+
+// nextResponse returns the next response in seq order
+func (cs *ClientStream) nextResponse() (*xrpcpb.Response, error) {
+	for {
+		// the messages buffered are dropped after closed
+		select {
+		case <-cs.closed:
+			return nil, cs.closedError()
+		default:
+		}
+
+		if response, ok := cs.pending[cs.next]; ok {
+			delete(cs.pending, cs.next)
+			cs.next++
+			return response, nil
+		}
+
+		select {
+		case <-cs.ctx.Done():
+			return nil, cs.ctx.Err()
+		case <-cs.closed:
+			return nil, cs.closedError()
+		case response := <-cs.msgs:
+			if response.Seq == 0 {
+				// the call failed before streaming
+				return response, nil
+			}
+			cs.pending[response.Seq] = response
+		}
+	}
+}
+
+// RecvMsg receives the next message into m.
+// It returns io.EOF when the stream ends successfully, or the error of the method.
+func (cs *ClientStream) RecvMsg(m any) error {
+	if cs.err != nil {
+		return cs.err
+	}
+
+	response, err := cs.nextResponse()
+	if err != nil {
+		cs.finish(err)
+		return err
+	}
+
+	if response.EndOfStream || response.Seq == 0 {
+		cs.final = response
+		cs.trailer = response.Metadata
+		if receiver := trailerReceiver(cs.ctx); receiver != nil {
+			*receiver = response.Metadata
+		}
+
+		err := cs.c.responseError(response)
+		if err == nil {
+			err = io.EOF
+		}
+		cs.finish(err)
+		return err
+	}
+
+	// grant the credits consumed when half of the window is used
+	cs.consumed++
+	if cs.consumed >= (cs.window+1)/2 {
+		if err := cs.grant(cs.consumed); err != nil {
+			glog.Error("grant error: ", err)
+		} else {
+			cs.consumed = 0
+		}
+	}
+
+	return cs.c.opts.codec.Unmarshal(response.Result, m)
+}
+
+// grant allows the server to send n more messages
+func (cs *ClientStream) grant(n int64) error {
+	cs.sendMu.Lock()
+	streamTo := cs.streamTo
+	cs.sendMu.Unlock()
+	if streamTo == "" {
+		return errors.New("stream not opened")
+	}
+
+	return cs.publish(streamTo, &xrpcpb.Request{
+		Cid:     cs.cid,
+		Credits: n,
+	})
+}
+
+// waitCredit waits for a credit to send, it returns the inbox of the server and the seq of the message
+func (cs *ClientStream) waitCredit() (string, int64, error) {
+	for {
+		cs.sendMu.Lock()
+		if cs.sendDone {
+			cs.sendMu.Unlock()
+			return "", 0, ErrStreamClosed
+		}
+		if cs.credits > 0 {
+			cs.credits--
+			cs.sendSeq++
+			streamTo, seq := cs.streamTo, cs.sendSeq
+			cs.sendMu.Unlock()
+			return streamTo, seq, nil
+		}
+		cs.sendMu.Unlock()
+
+		select {
+		case <-cs.granted:
+		case <-cs.ctx.Done():
+			return "", 0, cs.ctx.Err()
+		case <-cs.closed:
+			return "", 0, ErrStreamClosed
+		}
+	}
+}
+
+// SendMsg sends m to the streaming method, it blocks until the server grants the credit,
+// so that a fast sender doesn't flood the server.
+// It returns ErrStreamClosed after the method returned, the status is received by RecvMsg.
+func (cs *ClientStream) SendMsg(m any) error {
+	data, err := cs.c.opts.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	streamTo, seq, err := cs.waitCredit()
+	if err != nil {
+		return err
+	}
+
+	return cs.publish(streamTo, &xrpcpb.Request{
+		Cid:    cs.cid,
+		Params: [][]byte{data},
+		Seq:    seq,
+	})
+}
+
+// CloseSend tells the server that the client finished sending, it waits for the stream to be opened
+func (cs *ClientStream) CloseSend() error {
+	for {
+		cs.sendMu.Lock()
+		if cs.sendDone {
+			cs.sendMu.Unlock()
+			return nil
+		}
+		if cs.streamTo != "" {
+			cs.sendDone = true
+			cs.sendSeq++
+			frame := &xrpcpb.Request{
+				Cid:         cs.cid,
+				Seq:         cs.sendSeq,
+				EndOfStream: true,
+			}
+			streamTo := cs.streamTo
+			cs.sendMu.Unlock()
+			return cs.publish(streamTo, frame)
+		}
+		cs.sendMu.Unlock()
+
+		select {
+		case <-cs.granted:
+		case <-cs.ctx.Done():
+			return cs.ctx.Err()
+		case <-cs.closed:
+			return nil
+		}
+	}
+}
+
+func (cs *ClientStream) publish(streamTo string, frame *xrpcpb.Request) error {
+	data, err := proto.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return cs.c.opts.mq.Publish(streamTo, data)
+}
+
+// cancel tells the server to cancel the context of the method
+func (cs *ClientStream) cancel(streamTo string) {
+	if err := cs.publish(streamTo, &xrpcpb.Request{Cid: cs.cid, Cancel: true}); err != nil {
+		glog.Error("cancel stream error: ", err)
+	}
+}
+
+// CloseAndRecv finishes sending of a client-streaming call, and receives the reply of the method
+func (cs *ClientStream) CloseAndRecv(reply any) error {
+	if err := cs.CloseSend(); err != nil {
+		return err
+	}
+
+	// the client-streaming method replies in the end of stream
+	for {
+		err := cs.RecvMsg(nil)
+		if err == io.EOF {
+			return cs.c.decodeReply(cs.final, reply)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Trailer returns the trailer set by the server, it's valid after RecvMsg returns io.EOF or the error of the method
+func (cs *ClientStream) Trailer() Metadata {
+	return cs.trailer
+}
+
+// Close stops the stream, the context of the method is canceled if it's still running.
+// If the stream is not opened yet, it's canceled when opened.
+func (cs *ClientStream) Close() {
+	cs.closeOnce.Do(func() {
+		cs.sendMu.Lock()
+		cs.sendDone = true
+		opened := cs.opened
+		cancel := !cs.ended && cs.streamTo != ""
+		streamTo := cs.streamTo
+		cs.sendMu.Unlock()
+
+		close(cs.closed)
+		if cancel {
+			cs.cancel(streamTo)
+		}
+		// wait for the server to open the stream to cancel it, see deliver
+		if opened {
+			cs.c.calls.Delete(cs.cid)
+		}
+	})
+}
+
+func (cs *ClientStream) finish(err error) {
+	cs.err = err
+	cs.Close()
+}
+
+// recvTyped receives a message of type T by recv, the pointer is allocated,
+// e.g. the proto codec needs *pb.Item instead of **pb.Item
+func recvTyped[T any](recv func(any) error) (T, error) {
+	var m T
+	rt := reflect.TypeOf(&m).Elem()
+	if rt.Kind() != reflect.Ptr {
+		err := recv(&m)
+		return m, err
+	}
+
+	v := reflect.New(rt.Elem())
+	if err := recv(v.Interface()); err != nil {
+		return m, err
+	}
+	return v.Interface().(T), nil
+}
+
+// StreamReader is the typed receiver of a server-streaming call, e.g.
+//
+//	stream, err := client.NewStream(ctx, subj, "List", "prefix")
+//	items := xrpc.StreamReader[*pb.Item]{ClientStream: stream}
+//	for {
+//		item, err := items.Recv()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+type StreamReader[T any] struct {
+	*ClientStream
+}
+
+// Recv receives the next message, see RecvMsg
+func (s *StreamReader[T]) Recv() (T, error) {
+	return recvTyped[T](s.RecvMsg)
+}
+
+// StreamWriter is the typed sender of a client-streaming call, e.g.
+//
+//	stream, err := client.NewStream(ctx, subj, "Upload", "name")
+//	chunks := xrpc.StreamWriter[[]byte]{ClientStream: stream}
+//	for _, chunk := range data {
+//		chunks.Send(chunk)
+//	}
+//	var size int
+//	err = chunks.CloseAndRecv(&size)
+type StreamWriter[T any] struct {
+	*ClientStream
+}
+
+// Send sends m to the method, see SendMsg
+func (s *StreamWriter[T]) Send(m T) error {
+	return s.SendMsg(m)
+}
+
+// StreamReadWriter is the typed stream of a bidirectional streaming call
+type StreamReadWriter[Req any, Resp any] struct {
+	*ClientStream
+}
+
+// Send sends m to the method, see SendMsg
+func (s *StreamReadWriter[Req, Resp]) Send(m Req) error {
+	return s.SendMsg(m)
+}
+
+// Recv receives the next message, see RecvMsg
+func (s *StreamReadWriter[Req, Resp]) Recv() (Resp, error) {
+	return recvTyped[Resp](s.RecvMsg)
+}
@@ -1,6 +1,7 @@
 package xrpc
 
 import (
+	"context"
 	"reflect"
 )
 
@@ -8,21 +9,51 @@ func isErrorType(t reflect.Type) bool {
 	return t.Implements(reflect.TypeOf((*error)(nil)).Elem())
 }
 
+func isContextType(t reflect.Type) bool {
+	return t == reflect.TypeOf((*context.Context)(nil)).Elem()
+}
+
+// isServerStreamType checks if t is a pointer to a struct embedding *ServerStream first,
+// such as *SendStream[T], *RecvStream[T] and *BidiStream[Req, Resp]
+func isServerStreamType(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct || t.Elem().NumField() == 0 {
+		return false
+	}
+	field := t.Elem().Field(0)
+	return field.Anonymous && field.Type == reflect.TypeOf((*ServerStream)(nil))
+}
+
 // suitableMethod checks if the method is suitable for registration
-// suitable method should have no return value or two return values
-// the second return value should be of type error
+// suitable method should have no return value, or the last return value should be of type error
+// the first arg can be context.Context, which is cancelled when the caller's deadline passes
+// the method can be variadic
+// the last arg can be the stream of a streaming method, which returns error only or a reply and error:
+// *SendStream[T] of server-streaming, *RecvStream[T] of client-streaming, *BidiStream[Req, Resp] of bidirectional streaming
 // e.g.
 //
 //	func (s *Service) Method(args)
+//	func (s *Service) Method(args) error
 //	func (s *Service) Method(args) (reply, error)
+//	func (s *Service) Method(args) (reply1, reply2, error)
+//	func (s *Service) Method(ctx context.Context, args) (reply, error)
+//	func (s *Service) Method(arg, args ...T) (reply, error)
+//	func (s *Service) Method(args, stream *xrpc.SendStream[T]) error
+//	func (s *Service) Method(args, stream *xrpc.RecvStream[T]) (reply, error)
+//	func (s *Service) Method(args, stream *xrpc.BidiStream[Req, Resp]) error
 func suitableMethod(mtype reflect.Type) bool {
+	if n := mtype.NumIn(); n > 0 && isServerStreamType(mtype.In(n-1)) {
+		return mtype.NumOut() > 0 && isErrorType(mtype.Out(mtype.NumOut()-1))
+	}
+
 	if mtype.NumOut() == 0 {
 		return true
 	}
 
-	if mtype.NumOut() != 2 || !isErrorType(mtype.Out(1)) {
-		return false
-	}
+	return isErrorType(mtype.Out(mtype.NumOut() - 1))
+}
 
-	return true
+// isRecvStreamType checks if the stream type t receives messages from the client
+func isRecvStreamType(t reflect.Type) bool {
+	_, ok := t.MethodByName("Recv")
+	return ok
 }
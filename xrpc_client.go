@@ -1,11 +1,14 @@
 package xrpc
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	gobcodec "github.com/yc90s/xrpc/codec/gob"
+	"github.com/yc90s/xrpc/mq"
 	xrpcpb "github.com/yc90s/xrpc/pb"
 
 	"github.com/golang/glog"
@@ -13,18 +16,37 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+var (
+	ErrTimeout error = NewError(DeadlineExceeded, "timeout")
+)
+
+// Replies receives the results of a method returning several values besides the error, e.g.
+//
+//	var q, r int
+//	err := client.Call(subj, "DivMod", xrpc.Replies{&q, &r}, 7, 2)
+//
+// A nil element skips the result. A reply other than Replies fails to receive several results.
+type Replies []any
+
 // 用于外部封装的接口
 type IRPCClient interface {
 	Call(subj string, methodName string, reply any, args ...any) error
 	Cast(subj string, methodName string, args ...any) error
+	CallContext(ctx context.Context, subj string, methodName string, reply any, args ...any) error
+	CastContext(ctx context.Context, subj string, methodName string, args ...any) error
+	Go(subj string, methodName string, reply any, args ...any) *Call
+	GoContext(ctx context.Context, subj string, methodName string, reply any, args ...any) *Call
+	NewStream(ctx context.Context, subj string, methodName string, args ...any) (*ClientStream, error)
 }
 
 // RPCClient is a rpc client, it must implement the MQCallback interface
 type RPCClient struct {
 	opts    Options
+	sub     mq.Subscription
 	calls   sync.Map
 	isValid bool
 	mu      sync.Mutex
+	batcher *microBatcher // nil if micro-batching is disabled
 }
 
 func NewRPCClient(opts ...Option) *RPCClient {
@@ -41,11 +63,13 @@ func NewRPCClient(opts ...Option) *RPCClient {
 	if rpc_client.opts.codec == nil {
 		rpc_client.opts.codec = gobcodec.NewCodec()
 	}
+	rpc_client.opts.clientInterceptor = chainClientInterceptors(rpc_client.opts.clientInterceptors)
+	rpc_client.batcher = newMicroBatcher(rpc_client, rpc_client.opts.batchLinger, rpc_client.opts.batchMaxSize)
 
-	rpc_client.isValid = true
-	err := rpc_client.opts.mq.Subscribe(rpc_client.opts.subj, rpc_client)
-	if err != nil {
-		rpc_client.isValid = false
+	sub, err := rpc_client.opts.mq.Subscribe(rpc_client.opts.subj, rpc_client)
+	if err == nil {
+		rpc_client.sub = sub
+		rpc_client.isValid = true
 	}
 	return rpc_client
 }
@@ -53,40 +77,139 @@ func NewRPCClient(opts ...Option) *RPCClient {
 // Call is a method to call a rpc method with reply
 // goroutine safe
 func (c *RPCClient) Call(subj string, methodName string, reply any, args ...any) error {
+	return c.CallContext(context.Background(), subj, methodName, reply, args...)
+}
+
+// CallContext is like Call, but it gives up when ctx is done.
+// If ctx has no deadline, the timeout of options is used.
+// The remaining deadline is carried to the server.
+// goroutine safe
+func (c *RPCClient) CallContext(ctx context.Context, subj string, methodName string, reply any, args ...any) error {
 	if !c.isValid {
 		err := c.retry()
 		if err != nil {
 			return err
 		}
 	}
-	return c._call(subj, methodName, reply, args...)
+
+	if c.opts.clientInterceptor == nil {
+		return c.invokeCall(ctx, subj, methodName, reply, args)
+	}
+	return c.opts.clientInterceptor(ctx, subj, methodName, reply, args, c.invokeCall)
 }
 
 // Cast is a method to call a rpc method without reply
 // goroutine safe
 func (c *RPCClient) Cast(subj string, methodName string, args ...any) error {
+	return c.CastContext(context.Background(), subj, methodName, args...)
+}
+
+// CastContext is like Cast, the deadline of ctx is carried to the server if it has one.
+// With SetMicroBatch, it returns nil once the request is queued, see SetMicroBatch.
+// goroutine safe
+func (c *RPCClient) CastContext(ctx context.Context, subj string, methodName string, args ...any) error {
 	if !c.isValid {
 		err := c.retry()
 		if err != nil {
 			return err
 		}
 	}
-	return c._cast(subj, methodName, args...)
+
+	if c.opts.clientInterceptor == nil {
+		return c._cast(ctx, subj, methodName, args...)
+	}
+	return c.opts.clientInterceptor(ctx, subj, methodName, nil, args, c.invokeCast)
+}
+
+// invokeCall is the UnaryInvoker of Call
+func (c *RPCClient) invokeCall(ctx context.Context, subj string, methodName string, reply any, args []any) error {
+	randCid, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	cid := randCid.String()
+
+	if c.opts.retryPolicy != nil && c.opts.retryPolicy.enabled(methodName) {
+		return c.callWithRetry(ctx, cid, subj, methodName, reply, args...)
+	}
+	return c._call(ctx, cid, subj, methodName, reply, args...)
+}
+
+// invokeCast is the UnaryInvoker of Cast
+func (c *RPCClient) invokeCast(ctx context.Context, subj string, methodName string, reply any, args []any) error {
+	return c._cast(ctx, subj, methodName, args...)
+}
+
+// withDefaultTimeout returns ctx with the timeout of options if it has no deadline,
+// defaultTimeout reports whether the timeout is used
+func (c *RPCClient) withDefaultTimeout(ctx context.Context) (_ context.Context, cancel context.CancelFunc, defaultTimeout bool) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}, false
+	}
+	ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+	return ctx, cancel, true
 }
 
-func (c *RPCClient) _cast(subj string, methodName string, args ...any) error {
+// marshalArgs encodes the args of a request by the codec
+func (c *RPCClient) marshalArgs(args []any) ([][]byte, error) {
 	var argsData [][]byte
 
 	for _, arg := range args {
 		data, err := c.opts.codec.Marshal(arg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		argsData = append(argsData, data)
 	}
+	return argsData, nil
+}
+
+// remainingTimeout returns the time left before the deadline of ctx, 0 means no deadline
+func remainingTimeout(ctx context.Context) (int64, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, nil
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+	return int64(remaining), nil
+}
+
+func (c *RPCClient) _cast(ctx context.Context, subj string, methodName string, args ...any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	timeout, err := remainingTimeout(ctx)
+	if err != nil {
+		return err
+	}
+
+	argsData, err := c.marshalArgs(args)
+	if err != nil {
+		return err
+	}
+	md, _ := FromOutgoingContext(ctx)
 	request := &xrpcpb.Request{
-		Method: methodName,
-		Params: argsData,
+		Method:   methodName,
+		Params:   argsData,
+		Timeout:  timeout,
+		Metadata: md,
+	}
+
+	deadline, _ := ctx.Deadline()
+	return c.publish(subj, request, deadline)
+}
+
+// publish sends the request, it's queued if micro-batching is enabled, see SetMicroBatch.
+// deadline is the deadline of the caller, zero if none.
+func (c *RPCClient) publish(subj string, request *xrpcpb.Request, deadline time.Time) error {
+	if c.batcher != nil {
+		c.batcher.add(subj, request, deadline)
+		return nil
 	}
 
 	requestData, err := proto.Marshal(request)
@@ -97,34 +220,34 @@ func (c *RPCClient) _cast(subj string, methodName string, args ...any) error {
 	return c.opts.mq.Publish(subj, requestData)
 }
 
-func (c *RPCClient) _call(subj string, methodName string, reply any, args ...any) error {
-	var argsData [][]byte
-
-	for _, arg := range args {
-		data, err := c.opts.codec.Marshal(arg)
-		if err != nil {
-			return err
-		}
-		argsData = append(argsData, data)
+func (c *RPCClient) _call(ctx context.Context, cid string, subj string, methodName string, reply any, args ...any) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	randCid, err := uuid.NewRandom()
+	ctx, cancel, defaultTimeout := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	remaining, err := remainingTimeout(ctx)
 	if err != nil {
 		return err
 	}
-	cid := randCid.String()
-	request := &xrpcpb.Request{
-		Cid:     cid,
-		ReplyTo: c.opts.subj,
-		Method:  methodName,
-		Params:  argsData,
-	}
 
-	requestData, err := proto.Marshal(request)
+	argsData, err := c.marshalArgs(args)
 	if err != nil {
 		return err
 	}
 
+	md, _ := FromOutgoingContext(ctx)
+	request := &xrpcpb.Request{
+		Cid:      cid,
+		ReplyTo:  c.opts.subj,
+		Method:   methodName,
+		Params:   argsData,
+		Timeout:  remaining,
+		Metadata: md,
+	}
+
 	doneChan := make(chan *xrpcpb.Response, 1)
 
 	c.calls.Store(cid, doneChan)
@@ -132,23 +255,74 @@ func (c *RPCClient) _call(subj string, methodName string, reply any, args ...any
 		c.calls.Delete(cid)
 	}()
 
-	err = c.opts.mq.Publish(subj, requestData)
+	deadline, _ := ctx.Deadline()
+	err = c.publish(subj, request, deadline)
 	if err != nil {
 		return err
 	}
 
-	timeout := time.After(c.opts.timeout)
 	select {
-	case <-timeout:
-		return errors.New("timeout")
+	case <-ctx.Done():
+		if defaultTimeout && ctx.Err() == context.DeadlineExceeded {
+			return ErrTimeout
+		}
+		return ctx.Err()
 	case response := <-doneChan:
-		if len(response.Error) > 0 {
-			return errors.New(response.Error)
+		if receiver := trailerReceiver(ctx); receiver != nil {
+			*receiver = response.Metadata
+		}
+		if err := c.responseError(response); err != nil {
+			return err
 		}
-		return c.opts.codec.Unmarshal(response.Result, reply)
+		return c.decodeReply(response, reply)
 	}
 }
 
+// responseError returns the error replied by the server, nil if succeeded
+func (c *RPCClient) responseError(response *xrpcpb.Response) error {
+	if response.Status != nil && Code(response.Status.Code) != OK {
+		return fromStatus(response.Status, c.opts.codec)
+	}
+	if len(response.Error) > 0 {
+		return errors.New(response.Error)
+	}
+	return nil
+}
+
+// decodeReply unmarshals the results of response into reply, which may be Replies
+func (c *RPCClient) decodeReply(response *xrpcpb.Response, reply any) error {
+	results := response.Results
+	if len(results) == 0 && response.Result != nil {
+		results = [][]byte{response.Result}
+	}
+
+	replies, ok := reply.(Replies)
+	if !ok {
+		// the reply is not wanted, or the method returns nothing but error
+		if reply == nil || len(results) == 0 {
+			return nil
+		}
+		// the method returning several values needs Replies
+		if len(results) > 1 {
+			return fmt.Errorf("replies num not match: 1 != %d", len(results))
+		}
+		return c.opts.codec.Unmarshal(results[0], reply)
+	}
+
+	if len(replies) != len(results) {
+		return fmt.Errorf("replies num not match: %d != %d", len(replies), len(results))
+	}
+	for i, r := range replies {
+		if r == nil {
+			continue
+		}
+		if err := c.opts.codec.Unmarshal(results[i], r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // must goroutine safe
 func (c *RPCClient) Callback(data []byte, mqerr error) {
 	if mqerr != nil {
@@ -164,18 +338,53 @@ func (c *RPCClient) Callback(data []byte, mqerr error) {
 		glog.Error(err)
 		return
 	}
-	if doneChan, ok := c.calls.Load(response.Cid); !ok {
+
+	// the responses of a batch are demultiplexed by Cid
+	if len(response.Batch) > 0 {
+		for _, r := range response.Batch {
+			c.handleResponse(r)
+		}
+		return
+	}
+	c.handleResponse(&response)
+}
+
+// handleResponse delivers the response to the call waiting for it
+func (c *RPCClient) handleResponse(response *xrpcpb.Response) {
+	call, ok := c.calls.Load(response.Cid)
+	if !ok {
 		glog.Error("cid not found: ", response.Cid)
-	} else {
-		doneChan.(chan *xrpcpb.Response) <- &response
+		return
+	}
+
+	switch call := call.(type) {
+	case chan *xrpcpb.Response:
+		// the replies after the first one are dropped, e.g. several servers subscribe the subject
+		select {
+		case call <- response:
+		default:
+			glog.Info("duplicate reply: ", response.Cid)
+		}
+	case *Call:
+		call.reply(response, nil)
+	case *ClientStream:
+		call.deliver(response)
+	case *multicall:
+		call.deliver(response)
 	}
 }
 
+// Close sends the requests queued by micro-batching, and stops receiving the replies
 func (c *RPCClient) Close() {
+	if c.batcher != nil {
+		c.batcher.flush()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.isValid {
-		c.opts.mq.UnSubscribe()
+		c.sub.Unsubscribe()
+		c.sub = nil
 		c.isValid = false
 	}
 }
@@ -192,11 +401,12 @@ func (c *RPCClient) retry() error {
 		return nil
 	}
 
-	err := c.opts.mq.Subscribe(c.opts.subj, c)
+	sub, err := c.opts.mq.Subscribe(c.opts.subj, c)
 	if err != nil {
 		return err
 	}
 	glog.Info("retry success", c.opts.subj)
+	c.sub = sub
 	c.isValid = true
 	return nil
 }
@@ -1,23 +1,33 @@
 package xrpc
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	gobcodec "github.com/yc90s/xrpc/codec/gob"
+	"github.com/yc90s/xrpc/mq"
 	xrpcpb "github.com/yc90s/xrpc/pb"
 
 	"github.com/golang/glog"
+	"github.com/google/uuid"
 	"google.golang.org/protobuf/proto"
 )
 
 var (
-	ErrRepeatedRegister  = errors.New("method already registered")
-	ErrMethodNotSuitable = errors.New("method not suitable")
+	ErrRepeatedRegister       = errors.New("method already registered")
+	ErrMethodNotSuitable      = errors.New("method not suitable")
+	ErrQueueGroupNotSupported = errors.New("queue group not supported by the mq")
+	ErrServerStarted          = errors.New("server already started")
+	ErrMethodNotFound         = errors.New("method not found")
+	ErrShutdownTimeout        = errors.New("shutdown timeout")
 )
 
 type MethodInfo struct {
@@ -26,6 +36,10 @@ type MethodInfo struct {
 	InType     []reflect.Type // method args
 	OutType    []reflect.Type // method return
 	Goroutine  bool
+	HasContext bool // the first arg is context.Context
+	Stream     bool // the last arg is the stream of a streaming method, see suitableMethod
+	recv       bool // the stream receives messages from the client
+	sem        semaphore
 }
 
 type RPCInfo struct {
@@ -38,14 +52,33 @@ type RPCInfo struct {
 // RPCServer is a rpc server, it must implement the MQCallback interface
 type RPCServer struct {
 	opts         Options
+	sub          mq.Subscription
+	mu           sync.RWMutex
+	draining     atomic.Bool // stop accepting requests
+	methodsMu    sync.RWMutex
 	methods      map[string]*MethodInfo
-	wg           sync.WaitGroup
-	executingNum atomic.Int64 // 正在执行的任务数量
+	dedup        *dedupCache // nil if deduplication is disabled
+	sem          semaphore   // limits the RegisterGO methods
+	pool         atomic.Pointer[workerPool]
+	wg           sync.WaitGroup // the accepted requests
+	running      sync.Map       // the requests being executed, *xrpcpb.Request -> start time
+	executingNum atomic.Int64   // 正在执行的任务数量
+
+	streams      sync.Map        // the streams being served, cid -> *ServerStream
+	inbox        mq.Subscription // the subscription of inboxSubject, nil before the first receiving stream
+	inboxSubject string          // the subject the clients of the streams send messages to
+
+	batches sync.Map // the entries of the batches being handled, *xrpcpb.Request -> *batchReply
+
+	healthMu sync.Mutex
+	serving  ServingStatus            // status of the whole server
+	services map[string]ServingStatus // status of each service
 }
 
 func NewRPCServer(opts ...Option) *RPCServer {
 	rpc_server := new(RPCServer)
 	rpc_server.methods = make(map[string]*MethodInfo)
+	rpc_server.serving = Serving
 	for _, o := range opts {
 		o(&rpc_server.opts)
 	}
@@ -53,19 +86,82 @@ func NewRPCServer(opts ...Option) *RPCServer {
 	if rpc_server.opts.codec == nil {
 		rpc_server.opts.codec = gobcodec.NewCodec()
 	}
+	if rpc_server.opts.serverID == "" {
+		rpc_server.opts.serverID = uuid.NewString()
+	}
+	rpc_server.opts.serverInterceptor = chainServerInterceptors(rpc_server.opts.serverInterceptors)
+
+	if rpc_server.opts.dedupTTL > 0 {
+		size := rpc_server.opts.dedupSize
+		if size <= 0 {
+			size = 10000
+		}
+		rpc_server.dedup = newDedupCache(rpc_server.opts.dedupTTL, size)
+	}
+	rpc_server.sem = newSemaphore(rpc_server.opts.maxConcurrency)
+
+	// built-in methods
+	rpc_server._register(ReflectMethod, rpc_server.reflect, false)
+	rpc_server._register(HealthMethod, rpc_server.health, false)
 
 	return rpc_server
 }
 
+// call invokes the method with args, the context is passed if the method needs it.
+// It implements UnaryHandler and returns the results without the trailing error.
+func (m *MethodInfo) call(ctx context.Context, args []any) ([]any, error) {
+	in := make([]reflect.Value, 0, len(m.InType))
+	if m.HasContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	for _, arg := range args {
+		rt := m.argType(len(in))
+		if arg == nil {
+			in = append(in, reflect.Zero(rt))
+		} else {
+			in = append(in, reflect.ValueOf(arg))
+		}
+	}
+	if m.Stream {
+		in = append(in, newStreamArg(ctx, m.InType[len(m.InType)-1]))
+	}
+
+	out := m.Method.Call(in)
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	if e := out[len(out)-1].Interface(); e != nil {
+		return nil, e.(error)
+	}
+
+	results := make([]any, len(out)-1)
+	for i := range results {
+		results[i] = out[i].Interface()
+	}
+	return results, nil
+}
+
+// argType returns the type of the i-th arg including context.Context,
+// the trailing args of a variadic method are of the element type.
+func (m *MethodInfo) argType(i int) reflect.Type {
+	last := len(m.InType) - 1
+	if m.MethodType.IsVariadic() && i >= last {
+		return m.InType[last].Elem()
+	}
+	return m.InType[i]
+}
+
 func (s *RPCServer) GetSubj() string {
 	return s.opts.subj
 }
 
-func (s *RPCServer) _register(name string, f interface{}, goroutine bool) error {
-	if _, ok := s.methods[name]; ok {
-		return ErrRepeatedRegister
-	}
+// ID returns the id of the server, see SetServerID
+func (s *RPCServer) ID() string {
+	return s.opts.serverID
+}
 
+func newMethodInfo(f interface{}, goroutine bool) (*MethodInfo, error) {
 	method := &MethodInfo{
 		Method:     reflect.ValueOf(f),
 		MethodType: reflect.TypeOf(f),
@@ -73,45 +169,208 @@ func (s *RPCServer) _register(name string, f interface{}, goroutine bool) error
 	}
 
 	if !suitableMethod(method.MethodType) {
-		return ErrMethodNotSuitable
+		return nil, ErrMethodNotSuitable
 	}
 
 	method.InType = make([]reflect.Type, method.MethodType.NumIn())
 	for i := 0; i < method.MethodType.NumIn(); i++ {
 		method.InType[i] = method.MethodType.In(i)
 	}
+	method.HasContext = len(method.InType) > 0 && isContextType(method.InType[0])
+	method.Stream = len(method.InType) > 0 && isServerStreamType(method.InType[len(method.InType)-1])
+	method.recv = method.Stream && isRecvStreamType(method.InType[len(method.InType)-1])
 
 	method.OutType = make([]reflect.Type, method.MethodType.NumOut())
 	for i := 0; i < method.MethodType.NumOut(); i++ {
 		method.OutType[i] = method.MethodType.Out(i)
 	}
+	return method, nil
+}
+
+func (s *RPCServer) _register(name string, f interface{}, goroutine bool) error {
+	method, err := newMethodInfo(f, goroutine)
+	if err != nil {
+		return err
+	}
+	if goroutine {
+		method.sem = newSemaphore(s.opts.methodConcurrency[name])
+	}
 
+	s.methodsMu.Lock()
+	defer s.methodsMu.Unlock()
+	if _, ok := s.methods[name]; ok {
+		return ErrRepeatedRegister
+	}
 	s.methods[name] = method
 	return nil
 }
 
+func (s *RPCServer) getMethod(name string) (*MethodInfo, bool) {
+	s.methodsMu.RLock()
+	defer s.methodsMu.RUnlock()
+	methodInfo, ok := s.methods[name]
+	return methodInfo, ok
+}
+
 func (s *RPCServer) Register(name string, f interface{}) error {
+	if isReservedMethod(name) {
+		return ErrReservedMethod
+	}
 	return s._register(name, f, false)
 }
 
 func (s *RPCServer) RegisterGO(name string, f interface{}) error {
+	if isReservedMethod(name) {
+		return ErrReservedMethod
+	}
 	return s._register(name, f, true)
 }
 
+// Unregister removes the method, the requests being executed are not affected.
+// goroutine safe
+func (s *RPCServer) Unregister(name string) error {
+	if isReservedMethod(name) {
+		return ErrReservedMethod
+	}
+
+	s.methodsMu.Lock()
+	defer s.methodsMu.Unlock()
+	if _, ok := s.methods[name]; !ok {
+		return ErrMethodNotFound
+	}
+	delete(s.methods, name)
+	return nil
+}
+
+// Replace swaps the method for f, it keeps running in a goroutine if registered by RegisterGO.
+// The requests being executed are not affected, the new requests are handled by f.
+// goroutine safe
+func (s *RPCServer) Replace(name string, f interface{}) error {
+	if isReservedMethod(name) {
+		return ErrReservedMethod
+	}
+
+	s.methodsMu.Lock()
+	defer s.methodsMu.Unlock()
+	old, ok := s.methods[name]
+	if !ok {
+		return ErrMethodNotFound
+	}
+
+	method, err := newMethodInfo(f, old.Goroutine)
+	if err != nil {
+		return err
+	}
+	// share the concurrency limit with the requests of the old one
+	method.sem = old.sem
+	s.methods[name] = method
+	return nil
+}
+
 func (s *RPCServer) Start() error {
-	err := s.opts.mq.Subscribe(s.opts.subj, s)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sub != nil {
+		return ErrServerStarted
+	}
+
+	var sub mq.Subscription
+	var err error
+	if s.opts.queueGroup != "" {
+		qs, ok := s.opts.mq.(mq.QueueSubscriber)
+		if !ok {
+			return ErrQueueGroupNotSupported
+		}
+		sub, err = qs.QueueSubscribe(s.opts.subj, s.opts.queueGroup, s)
+	} else {
+		sub, err = s.opts.mq.Subscribe(s.opts.subj, s)
+	}
 	if err != nil {
 		return err
 	}
+
+	s.sub = sub
+	s.draining.Store(false)
+	if s.opts.poolWorkers > 0 {
+		s.pool.Store(newWorkerPool(s.opts.poolWorkers, s.opts.poolQueueSize))
+	}
 	return nil
 }
 
+// Stop shuts down the server and waits for all the executing methods, see Shutdown
 func (s *RPCServer) Stop() {
-	// unsubscribe
-	s.opts.mq.UnSubscribe()
+	s.Shutdown(context.Background())
+}
+
+// Shutdown stops accepting requests, the requests not executed yet are replied ErrServerStopped,
+// then it waits for the executing methods until ctx is done.
+// The subject is subscribed until the methods return, so that HealthMethod reports Draining meanwhile.
+// If ctx is done first, it returns an error listing the methods still running,
+// the streams of them keep working, the subject and the stream inbox are unsubscribed after they return.
+func (s *RPCServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining.Store(true)
+	s.mu.Unlock()
+
+	// the queued tasks reply ErrServerStopped since the server is draining
+	if pool := s.pool.Swap(nil); pool != nil {
+		for _, task := range pool.close() {
+			task()
+		}
+	}
 
 	// wait for executing tasks
-	s.wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.unsubscribe()
+		s.closeInbox()
+		return nil
+	case <-ctx.Done():
+		// the running streaming methods still need the inbox
+		go func() {
+			<-done
+			s.unsubscribe()
+			s.closeInbox()
+		}()
+
+		var handlers []string
+		s.running.Range(func(key, value any) bool {
+			request := key.(*xrpcpb.Request)
+			handlers = append(handlers, fmt.Sprintf("%s(cid:%s, %v)",
+				request.Method, request.Cid, time.Since(value.(time.Time)).Round(time.Millisecond)))
+			return true
+		})
+		sort.Strings(handlers)
+		return fmt.Errorf("%w: %w, %d handlers still running: %s", ErrShutdownTimeout, ctx.Err(), len(handlers), strings.Join(handlers, ", "))
+	}
+}
+
+// unsubscribe stops receiving the requests
+func (s *RPCServer) unsubscribe() {
+	s.mu.Lock()
+	sub := s.sub
+	s.sub = nil
+	s.mu.Unlock()
+	if sub != nil {
+		sub.Unsubscribe()
+	}
+}
+
+// accept counts the request into wg, it returns false if the server is shutting down
+func (s *RPCServer) accept() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.draining.Load() {
+		return false
+	}
+	s.wg.Add(1)
+	return true
 }
 
 // Callback is the callback function of handle message or error, it must be goroutine safe
@@ -132,42 +391,180 @@ func (s *RPCServer) Callback(data []byte, mqerr error) {
 		return
 	}
 
-	methodInfo, ok := s.methods[request.Method]
+	if len(request.Batch) > 0 {
+		s.handleBatch(start, &request)
+		return
+	}
+	s.dispatch(start, &request)
+}
+
+// dispatch handles the request, it returns false if the request is dropped without response
+func (s *RPCServer) dispatch(start time.Time, request *xrpcpb.Request) bool {
+	if s.dedup != nil && request.Cid != "" && s.isDuplicate(request) {
+		return false
+	}
+
+	methodInfo, ok := s.getMethod(request.Method)
 	if !ok {
 		glog.Info("method not found: ", request.Method)
-		return
+		s.reject(request, Errorf(Unimplemented, "method not found: %s", request.Method))
+		return true
+	}
+
+	if !s.accept() {
+		if request.Method == HealthMethod {
+			s.replyHealth(request)
+			return true
+		}
+		s.reject(request, ErrServerStopped)
+		return true
 	}
 
 	if methodInfo.Goroutine {
-		go s._runFunc(start, methodInfo, &request)
-	} else {
-		s._runFunc(start, methodInfo, &request)
+		return s._goFunc(start, methodInfo, request)
+	}
+	s._runFunc(start, methodInfo, request)
+	return true
+}
+
+// ExecutingNum returns the number of the methods being executed
+func (s *RPCServer) ExecutingNum() int64 {
+	return s.executingNum.Load()
+}
+
+// _goFunc runs the RegisterGO method in a new goroutine or the worker pool within the concurrency limits,
+// it returns false if the request is dropped by the overload policy
+func (s *RPCServer) _goFunc(start time.Time, methodInfo *MethodInfo, request *xrpcpb.Request) bool {
+	block := s.opts.overloadPolicy == OverloadBlock
+	if !methodInfo.sem.acquire(block) {
+		s.wg.Done()
+		return s.overload(request)
+	}
+	if !s.sem.acquire(block) {
+		methodInfo.sem.release()
+		s.wg.Done()
+		return s.overload(request)
+	}
+
+	task := func() {
+		defer func() {
+			s.sem.release()
+			methodInfo.sem.release()
+		}()
+
+		// the queued request is not executed if the server is shutting down
+		if s.draining.Load() {
+			s.wg.Done()
+			s.reject(request, ErrServerStopped)
+			return
+		}
+		s._runFunc(start, methodInfo, request)
+	}
+
+	if s.opts.poolWorkers <= 0 {
+		go task()
+		return true
+	}
+
+	pool := s.pool.Load()
+	if pool == nil || !pool.submit(task, block) {
+		s.sem.release()
+		methodInfo.sem.release()
+		s.wg.Done()
+		if s.pool.Load() != pool {
+			// the pool is closed by Stop
+			s.reject(request, ErrServerStopped)
+			return true
+		}
+		return s.overload(request)
 	}
+	return true
 }
 
+// overload handles the request rejected by the concurrency limits according to the overload policy,
+// it returns false if the request is dropped
+func (s *RPCServer) overload(request *xrpcpb.Request) bool {
+	if s.opts.overloadPolicy == OverloadDrop {
+		glog.Info("overloaded, drop request: ", request.Method)
+		if s.dedup != nil && request.Cid != "" {
+			s.dedup.forget(request.Cid)
+		}
+		return false
+	}
+
+	glog.Info("overloaded, reject request: ", request.Method)
+	s.reject(request, ErrOverloaded)
+	return true
+}
+
+// _runFunc executes the accepted request, and marks it done in wg
 func (s *RPCServer) _runFunc(start time.Time, methodInfo *MethodInfo, request *xrpcpb.Request) {
-	s.wg.Add(1)
 	s.executingNum.Add(1)
+	s.running.Store(request, start)
 	defer func() {
-		s.wg.Done()
-		s.executingNum.Add(-1)
-
 		if r := recover(); r != nil {
 			buf := make([]byte, 4096)
 			n := runtime.Stack(buf, false)
 			glog.Error("runFunc panic: ", r, string(buf[:n]))
+			s.replyError(request, Errorf(Internal, "method panic: %v", r))
 		}
+
+		s.running.Delete(request)
+		s.executingNum.Add(-1)
+		s.wg.Done()
 	}()
 
-	if len(request.Params) != len(methodInfo.InType) {
+	offset := 0
+	if methodInfo.HasContext {
+		offset = 1
+	}
+
+	if request.Stream != methodInfo.Stream {
+		glog.Info("stream not match: ", request.Method)
+		s.replyError(request, Errorf(FailedPrecondition, "stream not match: %s", request.Method))
+		return
+	}
+
+	// a variadic method can be called without the variadic args
+	numIn := len(methodInfo.InType) - offset
+	if methodInfo.Stream {
+		numIn--
+	}
+	if methodInfo.MethodType.IsVariadic() && len(request.Params) >= numIn-1 {
+		numIn = len(request.Params)
+	}
+	if len(request.Params) != numIn {
 		glog.Info("args num not match: ", request.Method)
+		s.replyError(request, Errorf(InvalidArgument, "args num not match: %s", request.Method))
 		return
 	}
 
-	var args = make([]reflect.Value, len(request.Params))
+	ctx := context.Background()
+	if request.Timeout > 0 {
+		// the deadline is counted from the time the request was received
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(time.Duration(request.Timeout)))
+		defer cancel()
+	}
+	ctx = newIncomingContext(ctx, request.Metadata)
+	ctx, trailer := newTrailerContext(ctx)
+	var stream *ServerStream
+	if methodInfo.Stream {
+		ctx, stream = newServerStream(ctx, s, request, methodInfo.recv)
+		defer stream.cancel()
+
+		if err := s.openStream(stream); err != nil {
+			glog.Error("open stream error: ", err)
+			s.reject(request, Errorf(Unavailable, "open stream error: %v", err))
+			return
+		}
+		defer s.streams.Delete(request.Cid)
+	}
+
+	var args = make([]any, len(request.Params))
 	for k, param := range request.Params {
 		var arg reflect.Value
-		rt := methodInfo.InType[k]
+		rt := methodInfo.argType(k + offset)
 		if rt.Kind() == reflect.Ptr {
 			arg = reflect.New(rt.Elem())
 		} else {
@@ -177,44 +574,62 @@ func (s *RPCServer) _runFunc(start time.Time, methodInfo *MethodInfo, request *x
 		err := s.opts.codec.Unmarshal(param, arg.Interface())
 		if err != nil {
 			glog.Info("Unmarshal error: ", err)
+			s.replyError(request, Errorf(InvalidArgument, "invalid arg %d: %v", k, err))
 			return
 		}
 
 		if rt.Kind() == reflect.Ptr {
-			args[k] = arg
+			args[k] = arg.Interface()
 		} else {
-			args[k] = arg.Elem()
+			args[k] = arg.Elem().Interface()
 		}
 	}
 
-	out := methodInfo.Method.Call(args)
-
-	if len(out) != len(methodInfo.OutType) {
-		glog.Info("reply num not match: ", request.Method)
-		return
+	var results []any
+	var err error
+	if s.opts.serverInterceptor == nil {
+		results, err = methodInfo.call(ctx, args)
+	} else {
+		header := &RequestHeader{
+			Cid:        request.Cid,
+			ReplyTo:    request.ReplyTo,
+			MethodName: request.Method,
+		}
+		results, err = s.opts.serverInterceptor(ctx, header, args, methodInfo.call)
 	}
 
 	response := &xrpcpb.Response{
-		Cid: request.Cid,
+		Cid:      request.Cid,
+		Metadata: trailer.metadata(),
 	}
 
-	var needReply bool
-	if len(out) > 0 {
-		needReply = true
-		switch e := out[1].Interface().(type) {
-		case nil:
-			response.Error = ""
-			b, err := s.opts.codec.Marshal(out[0].Interface())
+	// method without return value don't need reply unless it failed
+	needReply := len(methodInfo.OutType) > 0 || err != nil
+	if stream != nil {
+		response.Seq = stream.end()
+		response.EndOfStream = true
+	}
+	if err != nil {
+		response.Error = err.Error()
+		response.Status = toStatus(err, s.opts.codec)
+	} else if len(results) > 0 {
+		data := make([][]byte, len(results))
+		for i, result := range results {
+			b, err := s.opts.codec.Marshal(result)
 			if err != nil {
-				glog.Info("proto.Marshal error: ", err)
+				glog.Info("codec.Marshal error: ", err)
+				s.replyError(request, Errorf(Internal, "marshal reply error: %v", err))
 				return
 			}
-			response.Result = b
-		case error:
-			response.Error = e.Error()
+			data[i] = b
+		}
+
+		// a single result is kept in Result for compatibility
+		if len(data) == 1 {
+			response.Result = data[0]
+		} else {
+			response.Results = data
 		}
-	} else {
-		needReply = false
 	}
 
 	rpcInfo := &RPCInfo{
@@ -226,15 +641,85 @@ func (s *RPCServer) _runFunc(start time.Time, methodInfo *MethodInfo, request *x
 	s.sendResponse(rpcInfo)
 }
 
+// replyError replies err to the caller if the request needs a reply
+func (s *RPCServer) replyError(request *xrpcpb.Request, err error) {
+	rpcInfo := &RPCInfo{
+		request: request,
+		response: &xrpcpb.Response{
+			Cid:    request.Cid,
+			Error:  err.Error(),
+			Status: toStatus(err, s.opts.codec),
+		},
+		needReply: true,
+	}
+	s.sendResponse(rpcInfo)
+}
+
+// reject replies err to the request rejected without running the method,
+// it's forgotten by the dedup cache so that it can be retried.
+func (s *RPCServer) reject(request *xrpcpb.Request, err error) {
+	if s.dedup != nil && request.Cid != "" {
+		s.dedup.forget(request.Cid)
+	}
+	s.replyError(request, err)
+}
+
+// isDuplicate reports whether the request has been handled or is being handled,
+// the remembered response is replied again if it has one.
+func (s *RPCServer) isDuplicate(request *xrpcpb.Request) bool {
+	first, done, response := s.dedup.begin(request.Cid)
+	if first {
+		return false
+	}
+
+	glog.Info("duplicate request: ", request.Cid)
+	if done && response != nil && request.ReplyTo != "" {
+		err := s.opts.mq.Publish(request.ReplyTo, response)
+		if err != nil {
+			glog.Error("mq.Publish error: ", err)
+		}
+	}
+	return true
+}
+
+// dedupFinish remembers the response of the request,
+// the request rejected without running the method has been forgotten by reject.
+func (s *RPCServer) dedupFinish(rpcInfo *RPCInfo, data []byte) {
+	if s.dedup == nil || rpcInfo.request.Cid == "" {
+		return
+	}
+	s.dedup.finish(rpcInfo.request.Cid, data)
+}
+
 func (s *RPCServer) sendResponse(rpcInfo *RPCInfo) {
+	// the response of a batch entry is sent by the batch
+	var batch *batchReply
+	if b, ok := s.batches.LoadAndDelete(rpcInfo.request); ok {
+		batch = b.(*batchReply)
+	}
+
 	if rpcInfo.request.ReplyTo == "" || !rpcInfo.needReply {
 		// if replyTo is empty or dont need reply then return
+		s.dedupFinish(rpcInfo, nil)
+		return
+	}
+
+	rpcInfo.response.Responder = s.opts.serverID
+	if batch != nil && s.dedup == nil {
+		// no need to marshal it alone
+		batch.done(rpcInfo.response)
 		return
 	}
 
 	data, err := proto.Marshal(rpcInfo.response)
 	if err != nil {
 		glog.Error("proto.Marshal error: ", err)
+		s.dedupFinish(rpcInfo, nil)
+		return
+	}
+	s.dedupFinish(rpcInfo, data)
+	if batch != nil {
+		batch.done(rpcInfo.response)
 		return
 	}
 
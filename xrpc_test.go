@@ -0,0 +1,1502 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	memorymq "github.com/yc90s/xrpc/mq/memory"
+)
+
+type notFoundError struct {
+	Key string
+}
+
+func (e *notFoundError) Error() string {
+	return "not found: " + e.Key
+}
+
+func init() {
+	RegisterDetail("xrpc.notFoundError", &notFoundError{})
+}
+
+func newTestPair(t *testing.T, serverOpts []Option, clientOpts []Option) (*RPCServer, *RPCClient) {
+	// server and client share one MQueen
+	q := memorymq.NewMQueen(memorymq.NewBroker())
+
+	s := NewRPCServer(append([]Option{
+		SetMQ(q),
+		SetSubj("test_server"),
+	}, serverOpts...)...)
+
+	s.Register("Hello", func(name string) (string, error) {
+		return "hello:" + name, nil
+	})
+	s.RegisterGO("Add", func(a int, b *int) (int, error) {
+		return a + *b, nil
+	})
+	s.Register("Sleep", func(ctx context.Context, d time.Duration) (bool, error) {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(d):
+			return true, nil
+		}
+	})
+	s.Register("Find", func(key string) (string, error) {
+		return "", &notFoundError{Key: key}
+	})
+	s.Register("Panic", func() (int, error) {
+		panic("boom")
+	})
+	s.Register("Metadata", func(ctx context.Context, key string) (string, error) {
+		md, _ := FromIncomingContext(ctx)
+		SetTrailer(ctx, NewMetadata("echo", md.Get(key)))
+		return md.Get(key), nil
+	})
+
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+
+	c := NewRPCClient(append([]Option{
+		SetMQ(q),
+		SetSubj("test_client"),
+		SetTimeout(time.Second),
+	}, clientOpts...)...)
+	t.Cleanup(c.Close)
+
+	return s, c
+}
+
+func TestCall(t *testing.T) {
+	_, c := newTestPair(t, nil, nil)
+
+	var reply string
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); err != nil {
+		t.Fatal(err)
+	}
+	if reply != "hello:xrpc" {
+		t.Errorf("reply:%s != hello:xrpc", reply)
+	}
+
+	var sum int
+	b := 2
+	if err := c.Call("test_server", "Add", &sum, 1, &b); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 3 {
+		t.Errorf("sum:%d != 3", sum)
+	}
+
+	if err := c.Cast("test_server", "Hello", "xrpc"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	_, c := newTestPair(t, nil, nil)
+
+	var ok bool
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.CallContext(ctx, "test_server", "Sleep", &ok, time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err:%v != context.DeadlineExceeded", err)
+	}
+
+	if err := c.Call("test_server", "Sleep", &ok, time.Millisecond); err != nil || !ok {
+		t.Errorf("ok:%v err:%v", ok, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, c := newTestPair(t, nil, nil)
+
+	var reply string
+	err := c.Call("test_server", "Find", &reply, "key")
+	if CodeOf(err) != Unknown {
+		t.Errorf("code:%v != Unknown", CodeOf(err))
+	}
+	var nf *notFoundError
+	if !errors.As(err, &nf) || nf.Key != "key" {
+		t.Errorf("err:%v is not notFoundError", err)
+	}
+
+	start := time.Now()
+	err = c.Call("test_server", "NotExist", &reply)
+	if CodeOf(err) != Unimplemented {
+		t.Errorf("code:%v != Unimplemented", CodeOf(err))
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("method not found should fail fast")
+	}
+
+	err = c.Call("test_server", "Hello", &reply)
+	if CodeOf(err) != InvalidArgument {
+		t.Errorf("code:%v != InvalidArgument", CodeOf(err))
+	}
+
+	var n int
+	err = c.Call("test_server", "Panic", &n)
+	if CodeOf(err) != Internal {
+		t.Errorf("code:%v != Internal", CodeOf(err))
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	var order []string
+	denied := NewError(PermissionDenied, "denied")
+	serverOpts := []Option{
+		SetServerInterceptor(
+			func(ctx context.Context, header *RequestHeader, args []any, handler UnaryHandler) ([]any, error) {
+				order = append(order, "s1:"+header.MethodName)
+				if header.MethodName == "Hello" && args[0] == "deny" {
+					return nil, denied
+				}
+				return handler(ctx, args)
+			},
+			func(ctx context.Context, header *RequestHeader, args []any, handler UnaryHandler) ([]any, error) {
+				order = append(order, "s2")
+				return handler(ctx, args)
+			},
+		),
+	}
+	clientOpts := []Option{
+		SetClientInterceptor(func(ctx context.Context, subj string, methodName string, reply any, args []any, invoker UnaryInvoker) error {
+			order = append(order, "c1")
+			return invoker(ctx, subj, methodName, reply, args)
+		}),
+	}
+	_, c := newTestPair(t, serverOpts, clientOpts)
+
+	var reply string
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"c1", "s1:Hello", "s2"}
+	if len(order) != len(want) {
+		t.Fatalf("order:%v != %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order:%v != %v", order, want)
+		}
+	}
+
+	err := c.Call("test_server", "Hello", &reply, "deny")
+	if !errors.Is(err, denied) {
+		t.Errorf("err:%v != %v", err, denied)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	_, c := newTestPair(t, nil, nil)
+
+	var trailer Metadata
+	ctx := AppendToOutgoingContext(context.Background(), "token", "secret")
+	ctx = WithTrailer(ctx, &trailer)
+
+	var reply string
+	if err := c.CallContext(ctx, "test_server", "Metadata", &reply, "token"); err != nil {
+		t.Fatal(err)
+	}
+	if reply != "secret" || trailer.Get("echo") != "secret" {
+		t.Errorf("reply:%s trailer:%v", reply, trailer)
+	}
+}
+
+func TestQueueGroup(t *testing.T) {
+	broker := memorymq.NewBroker()
+
+	var handled atomic.Int64
+	for i := 0; i < 3; i++ {
+		s := NewRPCServer(
+			SetMQ(memorymq.NewMQueen(broker)),
+			SetSubj("group_server"),
+			SetQueueGroup("group"),
+		)
+		s.Register("Count", func() (int64, error) {
+			return handled.Add(1), nil
+		})
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.Stop)
+	}
+
+	c := NewRPCClient(SetMQ(memorymq.NewMQueen(broker)), SetSubj("group_client"))
+	t.Cleanup(c.Close)
+
+	for i := 1; i <= 10; i++ {
+		var n int64
+		if err := c.Call("group_server", "Count", &n); err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(i) {
+			t.Fatalf("request handled %d times, want once", n-int64(i)+1)
+		}
+	}
+}
+
+func TestGo(t *testing.T) {
+	_, c := newTestPair(t, nil, nil)
+
+	calls := make([]*Call, 10)
+	for i := range calls {
+		b := i
+		calls[i] = c.Go("test_server", "Add", new(int), i, &b)
+	}
+	for i, call := range calls {
+		if err := call.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if sum := *call.Reply.(*int); sum != 2*i {
+			t.Errorf("sum:%d != %d", sum, 2*i)
+		}
+	}
+
+	call := <-c.Go("test_server", "NotExist", new(int)).Done
+	if CodeOf(call.Error) != Unimplemented {
+		t.Errorf("code:%v != Unimplemented", CodeOf(call.Error))
+	}
+}
+
+func TestGoPending(t *testing.T) {
+	release := make(chan struct{})
+	s, c := newTestPair(t, nil, []Option{SetTimeout(200 * time.Millisecond)})
+	s.Register("Block", func(n int) (int, error) {
+		<-release
+		return n, nil
+	})
+
+	// the pending calls are completed by the replies, no goroutine waits for each
+	before := runtime.NumGoroutine()
+	calls := make([]*Call, 100)
+	for i := range calls {
+		calls[i] = c.Go("test_server", "Block", new(int), i)
+	}
+	if n := runtime.NumGoroutine() - before; n > 10 {
+		t.Errorf("%d goroutines for 100 pending calls", n)
+	}
+	close(release)
+	for i, call := range calls {
+		if err := call.Wait(); err != nil || *call.Reply.(*int) != i {
+			t.Fatalf("reply:%d err:%v", *call.Reply.(*int), err)
+		}
+	}
+
+	// no server
+	if err := c.Go("nobody", "Block", new(int), 1).Wait(); err != ErrTimeout {
+		t.Errorf("err:%v != ErrTimeout", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	call := c.GoContext(ctx, "nobody", "Block", new(int), 1)
+	cancel()
+	if err := call.Wait(); err != context.Canceled {
+		t.Errorf("err:%v != %v", err, context.Canceled)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var cids []string
+	serverOpts := []Option{
+		SetServerInterceptor(func(ctx context.Context, header *RequestHeader, args []any, handler UnaryHandler) ([]any, error) {
+			cids = append(cids, header.Cid)
+			if len(cids) < 3 {
+				return nil, NewError(Unavailable, "unavailable")
+			}
+			return handler(ctx, args)
+		}),
+	}
+	clientOpts := []Option{
+		SetRetryPolicy(RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Jitter:         0.2,
+			Methods:        []string{"Hello"},
+		}),
+	}
+	_, c := newTestPair(t, serverOpts, clientOpts)
+
+	var reply string
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); err != nil {
+		t.Fatal(err)
+	}
+	if len(cids) != 3 || cids[0] != cids[1] || cids[1] != cids[2] {
+		t.Errorf("cids:%v should be 3 attempts with the same cid", cids)
+	}
+
+	// not opted in
+	cids = cids[:0]
+	var sum int
+	b := 1
+	err := c.Call("test_server", "Add", &sum, 1, &b)
+	if CodeOf(err) != Unavailable || len(cids) != 1 {
+		t.Errorf("err:%v attempts:%d, should not retry", err, len(cids))
+	}
+}
+
+func TestDedup(t *testing.T) {
+	var executed atomic.Int64
+	s, c := newTestPair(t, []Option{SetDedup(time.Minute, 100)}, []Option{
+		SetRetryPolicy(RetryPolicy{
+			MaxAttempts:       5,
+			PerAttemptTimeout: 50 * time.Millisecond,
+			Methods:           []string{"*"},
+		}),
+	})
+	s.RegisterGO("Incr", func() (int64, error) {
+		time.Sleep(80 * time.Millisecond)
+		return executed.Add(1), nil
+	})
+
+	var n int64
+	if err := c.Call("test_server", "Incr", &n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || executed.Load() != 1 {
+		t.Errorf("executed %d times, want once", executed.Load())
+	}
+}
+
+func TestDedupHandlerError(t *testing.T) {
+	// Unavailable returned by the method is remembered, the retry doesn't run it again
+	var executed atomic.Int64
+	s, c := newTestPair(t, []Option{SetDedup(time.Minute, 100)}, []Option{
+		SetRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			Methods:     []string{"*"},
+		}),
+	})
+	s.Register("Charge", func() (int64, error) {
+		executed.Add(1)
+		return 0, NewError(Unavailable, "downstream unavailable")
+	})
+
+	var n int64
+	if err := c.Call("test_server", "Charge", &n); CodeOf(err) != Unavailable {
+		t.Errorf("code:%v != Unavailable", CodeOf(err))
+	}
+	if executed.Load() != 1 {
+		t.Errorf("executed %d times, want once", executed.Load())
+	}
+}
+
+func TestDedupMethodNotFound(t *testing.T) {
+	// the request of a missing method is not run, the retry after it's registered runs it
+	s, c := newTestPair(t, []Option{SetDedup(time.Minute, 100)}, nil)
+
+	var n int
+	if err := c._call(context.Background(), "late-cid", "test_server", "Late", &n); CodeOf(err) != Unimplemented {
+		t.Errorf("code:%v != Unimplemented", CodeOf(err))
+	}
+	s.Register("Late", func() (int, error) {
+		return 1, nil
+	})
+	if err := c._call(context.Background(), "late-cid", "test_server", "Late", &n); err != nil || n != 1 {
+		t.Errorf("n:%d err:%v", n, err)
+	}
+}
+
+func TestDedupCache(t *testing.T) {
+	cache := newDedupCache(time.Minute, 2)
+
+	if first, _, _ := cache.begin("a"); !first {
+		t.Error("a should be the first")
+	}
+	if first, done, _ := cache.begin("a"); first || done {
+		t.Error("a should be handling")
+	}
+	cache.finish("a", []byte("reply"))
+	if first, done, response := cache.begin("a"); first || !done || string(response) != "reply" {
+		t.Error("a should be done with reply")
+	}
+
+	cache.begin("b")
+	cache.begin("c")
+	if first, _, _ := cache.begin("a"); !first {
+		t.Error("a should be evicted")
+	}
+
+	cache.forget("c")
+	if first, _, _ := cache.begin("c"); !first {
+		t.Error("c should be forgotten")
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	s, c := newTestPair(t, []Option{
+		SetMethodConcurrency("Block", 1),
+		SetOverloadPolicy(OverloadReject),
+	}, nil)
+	s.RegisterGO("Block", func() (bool, error) {
+		<-release
+		return true, nil
+	})
+
+	call := c.Go("test_server", "Block", new(bool))
+	waitExecuting(t, s, 1)
+
+	var ok bool
+	if err := c.Call("test_server", "Block", &ok); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("err:%v != %v", err, ErrOverloaded)
+	}
+	// other methods are not limited
+	var sum int
+	b := 2
+	if err := c.Call("test_server", "Add", &sum, 1, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	if err := call.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	release := make(chan struct{})
+	s, c := newTestPair(t, []Option{
+		SetWorkerPool(1, 1),
+		SetOverloadPolicy(OverloadReject),
+	}, nil)
+	s.RegisterGO("Block", func() (bool, error) {
+		<-release
+		return true, nil
+	})
+
+	call := c.Go("test_server", "Block", new(bool))
+	waitExecuting(t, s, 1)
+
+	// the only worker is busy and the queue is full
+	if err := c.Cast("test_server", "Block"); err != nil {
+		t.Fatal(err)
+	}
+	var sum int
+	b := 2
+	if err := c.Call("test_server", "Add", &sum, 1, &b); CodeOf(err) != ResourceExhausted {
+		t.Errorf("code:%v != ResourceExhausted", CodeOf(err))
+	}
+
+	close(release)
+	if err := call.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call("test_server", "Add", &sum, 1, &b); err != nil || sum != 3 {
+		t.Errorf("sum:%d err:%v", sum, err)
+	}
+}
+
+func waitExecuting(t *testing.T, s *RPCServer, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for s.ExecutingNum() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("executing:%d != %d", s.ExecutingNum(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	release := make(chan struct{})
+	s, c := newTestPair(t, []Option{SetWorkerPool(1, 1)}, nil)
+	s.RegisterGO("Block", func() (bool, error) {
+		<-release
+		return true, nil
+	})
+
+	executing := c.Go("test_server", "Block", new(bool))
+	waitExecuting(t, s, 1)
+	queued := c.Go("test_server", "Block", new(bool))
+	pool := s.pool.Load()
+	for deadline := time.Now().Add(time.Second); len(pool.tasks) != 1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("request not queued")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	if !errors.Is(err, ErrShutdownTimeout) || !strings.Contains(err.Error(), "Block") {
+		t.Errorf("err:%v should list the running Block", err)
+	}
+	if err := queued.Wait(); CodeOf(err) != Unavailable {
+		t.Errorf("code:%v != Unavailable", CodeOf(err))
+	}
+
+	close(release)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := executing.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdownStream(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+	s.RegisterGO("Sum", func(stream *RecvStream[int]) (int, error) {
+		sum := 0
+		for {
+			n, err := stream.Recv()
+			if err == io.EOF {
+				return sum, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+			sum += n
+		}
+	})
+
+	cs, err := c.NewStream(context.Background(), "test_server", "Sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := StreamWriter[int]{ClientStream: cs}
+	if err := numbers.Send(1); err != nil {
+		t.Fatal(err)
+	}
+	waitExecuting(t, s, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, ErrShutdownTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err:%v should be a shutdown timeout", err)
+	}
+
+	// the stream still works after the shutdown timeout
+	if err := numbers.Send(2); err != nil {
+		t.Fatal(err)
+	}
+	var sum int
+	if err := numbers.CloseAndRecv(&sum); err != nil || sum != 3 {
+		t.Errorf("sum:%d err:%v", sum, err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReflect(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+
+	if err := s.Register(ReflectMethod, func() {}); err != ErrReservedMethod {
+		t.Errorf("err:%v != %v", err, ErrReservedMethod)
+	}
+
+	methods, err := c.Reflect(context.Background(), "test_server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	descs := make(map[string]string)
+	for _, m := range methods {
+		descs[m.Name] = fmt.Sprintf("%v %v %v %v", m.Args, m.Returns, m.Goroutine, m.HasContext)
+	}
+	want := map[string]string{
+		"Add":         "[int *int] [int] true false",
+		"Sleep":       "[time.Duration] [bool] false true",
+		ReflectMethod: "[] [*xrpcpb.ReflectReply] false false",
+	}
+	for name, desc := range want {
+		if descs[name] != desc {
+			t.Errorf("%s:%q != %q", name, descs[name], desc)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+	s.SetServingStatus("Hello", Serving)
+	s.SetServingStatus("Sleep", NotServing)
+
+	health, err := c.CheckHealth("test_server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != Serving || health.Services["Hello"] != Serving || health.Services["Sleep"] != NotServing {
+		t.Errorf("health:%+v", health)
+	}
+	if health.Services["NotExist"] != ServingUnknown {
+		t.Errorf("NotExist:%v != UNKNOWN", health.Services["NotExist"])
+	}
+
+	s.SetServingStatus("", NotServing)
+	if health, err = c.CheckHealth("test_server"); err != nil || health.Status != NotServing {
+		t.Errorf("status:%v err:%v", health.Status, err)
+	}
+
+	// the health is reported while Shutdown waits for a running method
+	release := make(chan struct{})
+	s.RegisterGO("Block", func() (bool, error) {
+		<-release
+		return true, nil
+	})
+	call := c.Go("test_server", "Block", new(bool))
+	waitExecuting(t, s, 1)
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	for deadline := time.Now().Add(time.Second); !s.draining.Load(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("not draining")
+		}
+	}
+
+	if health, err = c.CheckHealth("test_server"); err != nil || health.Status != Draining ||
+		health.Services["Hello"] != Draining || health.ExecutingNum != 1 {
+		t.Errorf("health:%+v err:%v should be draining", health, err)
+	}
+	var reply string
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); !errors.Is(err, ErrServerStopped) {
+		t.Errorf("err:%v != %v", err, ErrServerStopped)
+	}
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if err := call.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.CheckHealthContext(ctx, "test_server"); err != context.DeadlineExceeded {
+		t.Errorf("err:%v, the server should be unsubscribed", err)
+	}
+}
+
+type arithService struct{}
+
+func (arithService) Add(a int, b int) (int, error) {
+	return a + b, nil
+}
+
+func (arithService) Mul(ctx context.Context, a int, b int) (int, error) {
+	return a * b, nil
+}
+
+func (arithService) String() string {
+	return "arith"
+}
+
+func TestRegisterService(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+
+	// String is skipped
+	if err := s.RegisterService("", arithService{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.getMethod("arithService.String"); ok {
+		t.Error("arithService.String should not be registered")
+	}
+
+	var n int
+	if err := c.Call("test_server", "arithService.Add", &n, 2, 3); err != nil || n != 5 {
+		t.Errorf("n:%d err:%v", n, err)
+	}
+	if err := c.Call("test_server", "arithService.Mul", &n, 2, 3); err != nil || n != 6 {
+		t.Errorf("n:%d err:%v", n, err)
+	}
+
+	err := s.RegisterServiceGO("arithService", &arithService{})
+	if !errors.Is(err, ErrRepeatedRegister) || errors.Is(err, ErrNoSuitableMethod) ||
+		!strings.Contains(err.Error(), "arithService.Add") || strings.Contains(err.Error(), "arithService.String") {
+		t.Errorf("err:%v should report the repeated methods only", err)
+	}
+
+	if err := s.RegisterService("stringer", struct{ fmt.Stringer }{}); !errors.Is(err, ErrNoSuitableMethod) {
+		t.Errorf("err:%v != %v", err, ErrNoSuitableMethod)
+	}
+}
+
+func TestUnregisterReplace(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+
+	var reply string
+	if err := s.Replace("Hello", func(name string) (string, error) {
+		return "hi:" + name, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); err != nil || reply != "hi:xrpc" {
+		t.Errorf("reply:%s err:%v", reply, err)
+	}
+
+	if err := s.Unregister("Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); CodeOf(err) != Unimplemented {
+		t.Errorf("code:%v != Unimplemented", CodeOf(err))
+	}
+
+	if err := s.Unregister("Hello"); err != ErrMethodNotFound {
+		t.Errorf("err:%v != %v", err, ErrMethodNotFound)
+	}
+	if err := s.Replace("Hello", func() {}); err != ErrMethodNotFound {
+		t.Errorf("err:%v != %v", err, ErrMethodNotFound)
+	}
+	if err := s.Unregister(HealthMethod); err != ErrReservedMethod {
+		t.Errorf("err:%v != %v", err, ErrReservedMethod)
+	}
+
+	// swap while calling
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.Replace("Add", func(a int, b *int) (int, error) {
+				return a + *b, nil
+			})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		var sum int
+		b := i
+		if err := c.Call("test_server", "Add", &sum, 1, &b); err != nil || sum != i+1 {
+			t.Errorf("sum:%d err:%v", sum, err)
+		}
+	}
+	<-done
+}
+
+func TestMultiReturnVariadic(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+	s.Register("DivMod", func(a int, b int) (int, int, error) {
+		return a / b, a % b, nil
+	})
+	s.Register("Check", func(ok bool) error {
+		if !ok {
+			return NewError(FailedPrecondition, "not ok")
+		}
+		return nil
+	})
+	s.RegisterGO("Sum", func(prefix string, nums ...int) (string, error) {
+		sum := 0
+		for _, n := range nums {
+			sum += n
+		}
+		return fmt.Sprint(prefix, sum), nil
+	})
+
+	var q, r int
+	if err := c.Call("test_server", "DivMod", Replies{&q, &r}, 7, 2); err != nil || q != 3 || r != 1 {
+		t.Errorf("q:%d r:%d err:%v", q, r, err)
+	}
+	if err := c.Call("test_server", "DivMod", Replies{&q}, 7, 2); err == nil {
+		t.Error("replies num not match should fail")
+	}
+	// several results don't fit a single reply
+	if err := c.Call("test_server", "DivMod", &q, 9, 2); err == nil || !strings.Contains(err.Error(), "replies num not match") {
+		t.Errorf("err:%v should be replies num not match", err)
+	}
+	if err := c.Call("test_server", "DivMod", nil, 9, 2); err != nil {
+		t.Error(err)
+	}
+
+	if err := c.Call("test_server", "Check", nil, true); err != nil {
+		t.Error(err)
+	}
+	if err := c.Call("test_server", "Check", nil, false); CodeOf(err) != FailedPrecondition {
+		t.Errorf("code:%v != FailedPrecondition", CodeOf(err))
+	}
+
+	var sum string
+	if err := c.Call("test_server", "Sum", &sum, "sum:", 1, 2, 3); err != nil || sum != "sum:6" {
+		t.Errorf("sum:%s err:%v", sum, err)
+	}
+	if err := c.Call("test_server", "Sum", &sum, "sum:"); err != nil || sum != "sum:0" {
+		t.Errorf("sum:%s err:%v", sum, err)
+	}
+	if err := c.Call("test_server", "Sum", &sum); CodeOf(err) != InvalidArgument {
+		t.Errorf("code:%v != InvalidArgument", CodeOf(err))
+	}
+
+	methods, err := c.Reflect(context.Background(), "test_server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range methods {
+		if m.Name == "Sum" && fmt.Sprint(m.Args) != "[string ...int]" {
+			t.Errorf("args:%v != [string ...int]", m.Args)
+		}
+		if m.Name == "DivMod" && fmt.Sprint(m.Returns) != "[int int]" {
+			t.Errorf("returns:%v != [int int]", m.Returns)
+		}
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	s, c := newTestPair(t, nil, nil)
+	s.RegisterGO("Count", func(n int, stream *SendStream[int]) error {
+		for i := 0; i < n; i++ {
+			if err := stream.Send(i); err != nil {
+				return err
+			}
+		}
+		if n < 0 {
+			return NewError(InvalidArgument, "negative")
+		}
+		SetTrailer(stream.Context(), NewMetadata("count", fmt.Sprint(n)))
+		return nil
+	})
+
+	cs, err := c.NewStream(context.Background(), "test_server", "Count", 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := StreamReader[int]{ClientStream: cs}
+	for i := 0; ; i++ {
+		n, err := numbers.Recv()
+		if err == io.EOF {
+			if i != 300 {
+				t.Errorf("received %d messages, want 300", i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != i {
+			t.Fatalf("n:%d != %d", n, i)
+		}
+	}
+	if cs.Trailer().Get("count") != "300" {
+		t.Errorf("trailer:%v", cs.Trailer())
+	}
+
+	cs, err = c.NewStream(context.Background(), "test_server", "Count", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := cs.RecvMsg(&n); CodeOf(err) != InvalidArgument {
+		t.Errorf("code:%v != InvalidArgument", CodeOf(err))
+	}
+
+	// streaming and unary calls don't mix
+	if err := c.Call("test_server", "Count", &n, 1); CodeOf(err) != FailedPrecondition {
+		t.Errorf("code:%v != FailedPrecondition", CodeOf(err))
+	}
+	cs, err = c.NewStream(context.Background(), "test_server", "Hello", "xrpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.RecvMsg(&n); CodeOf(err) != FailedPrecondition {
+		t.Errorf("code:%v != FailedPrecondition", CodeOf(err))
+	}
+
+	methods, err := c.Reflect(context.Background(), "test_server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range methods {
+		if m.Name == "Count" && (fmt.Sprint(m.Args) != "[int]" || m.Stream != "int") {
+			t.Errorf("args:%v stream:%s", m.Args, m.Stream)
+		}
+	}
+}
+
+func TestServerStreamUnordered(t *testing.T) {
+	// messages are handled concurrently by the mq, the client reorders them by seq
+	q := memorymq.NewMQueen(memorymq.NewBroker(memorymq.SetOrdered(false)))
+	s := NewRPCServer(SetMQ(q), SetSubj("stream_server"))
+	s.Register("Count", func(n int, stream *SendStream[int]) error {
+		for i := 0; i < n; i++ {
+			stream.Send(i)
+		}
+		return nil
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	c := NewRPCClient(SetMQ(q), SetSubj("stream_client"))
+	t.Cleanup(c.Close)
+
+	cs, err := c.NewStream(context.Background(), "stream_server", "Count", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		var n int
+		if err := cs.RecvMsg(&n); err == io.EOF {
+			break
+		} else if err != nil || n != i {
+			t.Fatalf("n:%d != %d err:%v", n, i, err)
+		}
+	}
+}
+
+func TestServerStreamUndrained(t *testing.T) {
+	s, c := newTestPair(t, nil, []Option{SetStreamWindow(8)})
+	s.RegisterGO("Count", func(n int, stream *SendStream[int]) error {
+		for i := 0; i < n; i++ {
+			if err := stream.Send(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// the server waits for the credits instead of blocking the client
+	cs, err := c.NewStream(context.Background(), "test_server", "Count", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	var reply string
+	if err := c.Call("test_server", "Hello", &reply, "xrpc"); err != nil || reply != "hello:xrpc" {
+		t.Fatalf("reply:%s err:%v", reply, err)
+	}
+
+	for i := 0; ; i++ {
+		var n int
+		if err := cs.RecvMsg(&n); err == io.EOF {
+			if i != 1000 {
+				t.Errorf("received %d messages, want 1000", i)
+			}
+			break
+		} else if err != nil || n != i {
+			t.Fatalf("n:%d != %d err:%v", n, i, err)
+		}
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	s, c := newTestPair(t, nil, []Option{SetTimeout(100 * time.Millisecond)})
+	stopped := make(chan error, 2)
+	s.RegisterGO("Tick", func(stream *SendStream[int]) error {
+		for i := 0; ; i++ {
+			if err := stream.Send(i); err != nil {
+				stopped <- err
+				return err
+			}
+		}
+	})
+	s.RegisterGO("Sink", func(stream *RecvStream[int]) (int, error) {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				stopped <- err
+				return 0, err
+			}
+		}
+	})
+
+	cs, err := c.NewStream(context.Background(), "test_server", "Tick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := cs.RecvMsg(&n); err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+	if err := cs.RecvMsg(&n); err != ErrStreamClosed {
+		t.Errorf("err:%v != ErrStreamClosed", err)
+	}
+
+	cs, err = c.NewStream(context.Background(), "test_server", "Sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SendMsg(1); err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-stopped:
+			if err != context.Canceled {
+				t.Errorf("err:%v != %v", err, context.Canceled)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the method is not canceled")
+		}
+	}
+	waitExecuting(t, s, 0)
+
+	// no server opens the stream
+	cs, err = c.NewStream(context.Background(), "nobody", "Tick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.RecvMsg(&n); err != ErrTimeout {
+		t.Errorf("err:%v != ErrTimeout", err)
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	// a small window makes the client wait for the credits
+	s, c := newTestPair(t, []Option{SetStreamWindow(2)}, nil)
+	s.RegisterGO("Sum", func(base int, stream *RecvStream[int]) (int, error) {
+		sum := base
+		for {
+			n, err := stream.Recv()
+			if err == io.EOF {
+				return sum, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+			if n < 0 {
+				return 0, NewError(InvalidArgument, "negative")
+			}
+			sum += n
+		}
+	})
+
+	cs, err := c.NewStream(context.Background(), "test_server", "Sum", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := StreamWriter[int]{ClientStream: cs}
+	for i := 1; i <= 100; i++ {
+		if err := numbers.Send(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sum int
+	if err := numbers.CloseAndRecv(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 1000+5050 {
+		t.Errorf("sum:%d != 6050", sum)
+	}
+
+	cs, err = c.NewStream(context.Background(), "test_server", "Sum", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.SendMsg(-1)
+	if err := cs.CloseAndRecv(&sum); CodeOf(err) != InvalidArgument {
+		t.Errorf("code:%v != InvalidArgument", CodeOf(err))
+	}
+	if err := cs.SendMsg(1); err != ErrStreamClosed {
+		t.Errorf("err:%v != ErrStreamClosed", err)
+	}
+
+	// the sender is stopped when the method returns without receiving all
+	s.Register("First", func(stream *RecvStream[int]) (int, error) {
+		return stream.Recv()
+	})
+	cs, err = c.NewStream(context.Background(), "test_server", "First")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; ; i++ {
+		if err := cs.SendMsg(i); err == ErrStreamClosed {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cs.CloseAndRecv(&sum); err != nil || sum != 1 {
+		t.Errorf("sum:%d err:%v", sum, err)
+	}
+
+	methods, err := c.Reflect(context.Background(), "test_server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range methods {
+		if m.Name == "Sum" && (fmt.Sprint(m.Args) != "[int]" || m.RecvStream != "int" || m.Stream != "") {
+			t.Errorf("args:%v recv:%s stream:%s", m.Args, m.RecvStream, m.Stream)
+		}
+	}
+}
+
+func TestClientStreamWindowExceeded(t *testing.T) {
+	s, c := newTestPair(t, []Option{SetStreamWindow(2)}, nil)
+	start := make(chan struct{})
+	s.RegisterGO("Sink", func(stream *RecvStream[int]) (int, error) {
+		<-start
+		n := 0
+		for {
+			if _, err := stream.Recv(); err != nil {
+				return n, err
+			}
+			n++
+		}
+	})
+
+	cs, err := c.NewStream(context.Background(), "test_server", "Sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a client ignoring the window
+	if err := cs.SendMsg(0); err != nil {
+		t.Fatal(err)
+	}
+	cs.sendMu.Lock()
+	cs.credits += 10
+	cs.sendMu.Unlock()
+	for i := 1; i <= 10; i++ {
+		if err := cs.SendMsg(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(start)
+	var n int
+	if err := cs.RecvMsg(&n); CodeOf(err) != ResourceExhausted {
+		t.Errorf("code:%v != ResourceExhausted", CodeOf(err))
+	}
+}
+
+func TestBidiStream(t *testing.T) {
+	q := memorymq.NewMQueen(memorymq.NewBroker(memorymq.SetOrdered(false)))
+	s := NewRPCServer(SetMQ(q), SetSubj("bidi_server"), SetStreamWindow(4))
+	s.Register("Echo", func(prefix string, stream *BidiStream[string, string]) error {
+		for {
+			m, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(prefix + m); err != nil {
+				return err
+			}
+		}
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	c := NewRPCClient(SetMQ(q), SetSubj("bidi_client"))
+	t.Cleanup(c.Close)
+
+	cs, err := c.NewStream(context.Background(), "bidi_server", "Echo", "echo:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo := StreamReadWriter[string, string]{ClientStream: cs}
+	for i := 0; i < 50; i++ {
+		if err := echo.Send(fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+		m, err := echo.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != fmt.Sprintf("echo:%d", i) {
+			t.Fatalf("m:%s", m)
+		}
+	}
+	if err := echo.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := echo.Recv(); err != io.EOF {
+		t.Errorf("err:%v != io.EOF", err)
+	}
+}
+
+func TestMulticall(t *testing.T) {
+	q := memorymq.NewMQueen(memorymq.NewBroker())
+	for i := 0; i < 3; i++ {
+		i := i
+		s := NewRPCServer(SetMQ(q), SetSubj("multi_server"), SetServerID(fmt.Sprint("server", i)))
+		s.Register("Stats", func() (int, error) {
+			if i == 2 {
+				return 0, NewError(NotFound, "no stats")
+			}
+			return i * 10, nil
+		})
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.Stop)
+	}
+	c := NewRPCClient(SetMQ(q), SetSubj("multi_client"), SetTimeout(200*time.Millisecond))
+	t.Cleanup(c.Close)
+
+	// wait until the deadline without a policy
+	replies, err := c.Multicall("multi_server", "Stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("replies:%d != 3", len(replies))
+	}
+	stats := make(map[string]int)
+	for _, r := range replies {
+		var n int
+		if err := r.Decode(&n); err != nil {
+			if r.Responder != "server2" || CodeOf(err) != NotFound {
+				t.Errorf("%s err:%v", r.Responder, err)
+			}
+			continue
+		}
+		stats[r.Responder] = n
+	}
+	if stats["server0"] != 0 || stats["server1"] != 10 || len(stats) != 2 {
+		t.Errorf("stats:%v", stats)
+	}
+
+	start := time.Now()
+	replies, err = c.MulticallContext(context.Background(), "multi_server", "Stats", MulticallPolicy{Expected: 3})
+	if err != nil || len(replies) != 3 {
+		t.Errorf("replies:%d err:%v", len(replies), err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("Multicall doesn't stop at the expected count")
+	}
+
+	replies, err = c.MulticallContext(context.Background(), "multi_server", "Stats", MulticallPolicy{Quorum: 2})
+	if err != nil || len(replies) < 2 {
+		t.Errorf("replies:%d err:%v", len(replies), err)
+	}
+
+	replies, err = c.MulticallContext(context.Background(), "multi_server", "Stats", MulticallPolicy{Quorum: 3})
+	if !errors.Is(err, ErrNoQuorum) || len(replies) != 3 {
+		t.Errorf("replies:%d err:%v", len(replies), err)
+	}
+
+	replies, err = c.MulticallContext(context.Background(), "multi_server", "Stats", MulticallPolicy{Expected: 4})
+	if err != ErrTimeout || len(replies) != 3 {
+		t.Errorf("replies:%d err:%v", len(replies), err)
+	}
+
+	// Call returns the first reply
+	var n int
+	if err := c.Call("multi_server", "Stats", &n); err != nil && CodeOf(err) != NotFound {
+		t.Error(err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s, c := newTestPair(t, []Option{SetDedup(time.Minute, 100)}, nil)
+	var logged atomic.Int64
+	s.Register("Log", func(n int) {
+		logged.Add(int64(n))
+	})
+
+	batch := c.NewBatch("test_server")
+	var hello string
+	helloCall := batch.Go("Hello", &hello, "xrpc")
+	sums := make([]int, 10)
+	addCalls := make([]*Call, 10)
+	for i := range sums {
+		b := i
+		addCalls[i] = batch.Go("Add", &sums[i], i, &b)
+	}
+	if err := batch.Cast("Log", 5); err != nil {
+		t.Fatal(err)
+	}
+	findCall := batch.Go("Find", nil, "key")
+	if batch.Len() != 13 {
+		t.Errorf("len:%d != 13", batch.Len())
+	}
+
+	// Do returns the first error
+	var nf *notFoundError
+	if err := batch.Do(context.Background()); !errors.As(err, &nf) {
+		t.Errorf("err:%v", err)
+	}
+	if batch.Len() != 0 {
+		t.Errorf("len:%d != 0", batch.Len())
+	}
+	if err := helloCall.Wait(); err != nil || hello != "hello:xrpc" {
+		t.Errorf("hello:%s err:%v", hello, err)
+	}
+	for i, call := range addCalls {
+		if err := call.Wait(); err != nil || sums[i] != 2*i {
+			t.Errorf("sum:%d err:%v", sums[i], err)
+		}
+	}
+	if err := findCall.Wait(); !errors.As(err, &nf) {
+		t.Errorf("err:%v", err)
+	}
+	if logged.Load() != 5 {
+		t.Errorf("logged:%d != 5", logged.Load())
+	}
+
+	// the entries fail alone
+	hello = ""
+	helloCall = batch.Go("Hello", &hello, "a")
+	missing := batch.Go("Missing", nil)
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := missing.Wait(); CodeOf(err) != Unimplemented {
+		t.Errorf("code:%v != Unimplemented", CodeOf(err))
+	}
+	if err := helloCall.Wait(); err != nil || hello != "hello:a" {
+		t.Errorf("hello:%s err:%v", hello, err)
+	}
+
+	// a slow RegisterGO entry doesn't delay the others
+	release := make(chan struct{})
+	s.RegisterGO("Slow", func() (bool, error) {
+		<-release
+		return true, nil
+	})
+	var slow bool
+	slowCall := batch.Go("Slow", &slow)
+	helloCall = batch.Go("Hello", &hello, "b")
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := helloCall.Wait(); err != nil || hello != "hello:b" {
+		t.Errorf("hello:%s err:%v", hello, err)
+	}
+	select {
+	case <-slowCall.Done:
+		t.Error("Slow done before released")
+	default:
+	}
+	close(release)
+	if err := slowCall.Wait(); err != nil || !slow {
+		t.Errorf("slow:%v err:%v", slow, err)
+	}
+
+	// the entry gives up at the deadline of the batch
+	sleep := batch.Go("Sleep", nil, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := batch.Do(ctx); CodeOf(err) != DeadlineExceeded {
+		t.Errorf("code:%v != DeadlineExceeded", CodeOf(err))
+	}
+	if err := sleep.Wait(); CodeOf(err) != DeadlineExceeded {
+		t.Errorf("code:%v != DeadlineExceeded", CodeOf(err))
+	}
+
+	// an empty batch sends nothing
+	if err := batch.Do(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+// countingMQ counts the messages published to each subject
+type countingMQ struct {
+	*memorymq.MQueen
+	mu        sync.Mutex
+	published map[string]int
+}
+
+func (q *countingMQ) Publish(subj string, data []byte) error {
+	q.mu.Lock()
+	q.published[subj]++
+	q.mu.Unlock()
+	return q.MQueen.Publish(subj, data)
+}
+
+func (q *countingMQ) count(subj string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.published[subj]
+}
+
+func TestMicroBatch(t *testing.T) {
+	q := &countingMQ{
+		MQueen:    memorymq.NewMQueen(memorymq.NewBroker()),
+		published: make(map[string]int),
+	}
+	s := NewRPCServer(SetMQ(q), SetSubj("batch_server"))
+	var logged atomic.Int64
+	s.Register("Log", func(n int) {
+		logged.Add(int64(n))
+	})
+	s.RegisterGO("Add", func(a, b int) (int, error) {
+		return a + b, nil
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	c := NewRPCClient(SetMQ(q), SetSubj("batch_client"), SetMicroBatch(50*time.Millisecond, 10))
+
+	// flushed on size
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var sum int
+			if err := c.Call("batch_server", "Add", &sum, i, i); err != nil || sum != 2*i {
+				t.Errorf("sum:%d err:%v", sum, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := q.count("batch_server"); n != 1 {
+		t.Errorf("published:%d != 1", n)
+	}
+
+	// flushed on time
+	for i := 0; i < 5; i++ {
+		if err := c.Cast("batch_server", "Log", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sum int
+	start := time.Now()
+	if err := c.Call("batch_server", "Add", &sum, 1, 2); err != nil || sum != 3 {
+		t.Errorf("sum:%d err:%v", sum, err)
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Error("the call is sent before linger")
+	}
+	if n := q.count("batch_server"); n != 2 {
+		t.Errorf("published:%d != 2", n)
+	}
+
+	// the deadline sooner than linger flushes earlier
+	ctx, cancel := context.WithTimeout(context.Background(), 40*time.Millisecond)
+	defer cancel()
+	if err := c.CallContext(ctx, "batch_server", "Add", &sum, 2, 2); err != nil || sum != 4 {
+		t.Errorf("sum:%d err:%v", sum, err)
+	}
+	if n := q.count("batch_server"); n != 3 {
+		t.Errorf("published:%d != 3", n)
+	}
+
+	// flushed on Close
+	c.Cast("batch_server", "Log", 10)
+	c.Close()
+	if n := q.count("batch_server"); n != 4 {
+		t.Errorf("published:%d != 4", n)
+	}
+	for i := 0; i < 100 && logged.Load() != 15; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if logged.Load() != 15 {
+		t.Errorf("logged:%d != 15", logged.Load())
+	}
+}
+
+func TestMicroBatchTimeout(t *testing.T) {
+	s, c := newTestPair(t, nil, []Option{SetMicroBatch(80*time.Millisecond, 10), SetTimeout(100 * time.Millisecond)})
+	s.Register("Remaining", func(ctx context.Context) (time.Duration, error) {
+		deadline, _ := ctx.Deadline()
+		return time.Until(deadline), nil
+	})
+
+	// the linger close to the timeout leaves half of the time to the server,
+	// which knows the time left instead of the timeout at queue time
+	for i := 0; i < 3; i++ {
+		var remaining time.Duration
+		if err := c.Call("test_server", "Remaining", &remaining); err != nil {
+			t.Fatal(err)
+		}
+		if remaining <= 0 || remaining > 60*time.Millisecond {
+			t.Errorf("remaining:%v should be about 50ms", remaining)
+		}
+	}
+}